@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// profile is connection settings of config file.
+// [default] section is applied to every connection, and [profiles.<name>] is applied by `mccat @name`
+type profile struct {
+	Servers          []string `toml:"servers"`
+	Protocol         string   `toml:"protocol"`
+	Username         string   `toml:"username"`
+	Password         string   `toml:"password"`
+	AuthFile         string   `toml:"auth_file"`
+	TLSCA            string   `toml:"tls_ca"`
+	TLSCert          string   `toml:"tls_cert"`
+	TLSKey           string   `toml:"tls_key"`
+	TLSServerName    string   `toml:"tls_server_name"`
+	TLSInsecure      bool     `toml:"tls_insecure"`
+	Timeout          string   `toml:"timeout"`
+	History          string   `toml:"history"`
+	DefaultTTL       int      `toml:"default_ttl"`
+	ReadOnly         bool     `toml:"read_only"`
+	Output           string   `toml:"output"`
+	KeySeparator     string   `toml:"key_separator"`
+	CompressionFlags string   `toml:"compression_flags"`
+	NoDecompress     bool     `toml:"no_decompress"`
+	Decode           string   `toml:"decode"`
+	Display          string   `toml:"display"`
+}
+
+type configFile struct {
+	Default  profile            `toml:"default"`
+	Profiles map[string]profile `toml:"profiles"`
+}
+
+// defaultConfigPath return $XDG_CONFIG_HOME/mccat/config.toml (or ~/.config/mccat/config.toml)
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+
+	return filepath.Join(dir, "mccat", "config.toml")
+}
+
+// expandHome replace leading ~/ of path to home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+
+	return path
+}
+
+// loadConfigFile read config file. missing file is not error unless path is given explicitly
+func loadConfigFile(path string, explicit bool) (*configFile, error) {
+	cf := &configFile{}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) && !explicit {
+		return cf, nil
+	}
+
+	if _, err := toml.DecodeFile(path, cf); err != nil {
+		return nil, fmt.Errorf("cannot read config file [%s]: %s", path, err.Error())
+	}
+
+	return cf, nil
+}
+
+// applyProfile set profile values to settings which are not given by flags
+func applyProfile(p profile, setFlags map[string]bool) error {
+	if len(p.Servers) > 0 {
+		servers = p.Servers
+	}
+
+	if p.Protocol != "" && !setFlags["protocol"] {
+		config.Protocol = p.Protocol
+	}
+	if !setFlags["auth"] {
+		if p.Username != "" && !setFlags["username"] {
+			config.Username = p.Username
+		}
+		if p.Password != "" && !setFlags["password"] {
+			config.Password = p.Password
+		}
+	}
+	if p.AuthFile != "" && !setFlags["auth-file"] {
+		authFile = expandHome(p.AuthFile)
+	}
+	if p.TLSCA != "" && !setFlags["tls-ca"] {
+		config.TLSCAFile = expandHome(p.TLSCA)
+	}
+	if p.TLSCert != "" && !setFlags["tls-cert"] {
+		config.TLSCertFile = expandHome(p.TLSCert)
+	}
+	if p.TLSKey != "" && !setFlags["tls-key"] {
+		config.TLSKeyFile = expandHome(p.TLSKey)
+	}
+	if p.TLSServerName != "" && !setFlags["tls-server-name"] {
+		config.TLSServerName = p.TLSServerName
+	}
+	if p.TLSInsecure && !setFlags["tls-insecure"] {
+		config.TLSInsecureSkipVerify = true
+	}
+	if p.Timeout != "" && !setFlags["timeout"] {
+		timeout, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return fmt.Errorf("timeout must be duration (ex: 500ms, 3s): %s", p.Timeout)
+		}
+		config.Timeout = timeout
+	}
+	if p.History != "" && !setFlags["history"] {
+		historyFile = expandHome(p.History)
+	}
+	if p.DefaultTTL > 0 {
+		config.DefaultTTL = p.DefaultTTL
+	}
+	if p.ReadOnly && !setFlags["read-only"] {
+		config.ReadOnly = true
+	}
+	if p.Output != "" && !setFlags["output"] {
+		config.Output = p.Output
+	}
+	if p.KeySeparator != "" {
+		config.KeySeparator = p.KeySeparator
+	}
+	if p.CompressionFlags != "" && !setFlags["compression-flags"] {
+		config.CompressionFlags = p.CompressionFlags
+	}
+	if p.NoDecompress && !setFlags["no-decompress"] {
+		config.NoDecompress = true
+	}
+	if p.Decode != "" && !setFlags["decode"] {
+		config.Decode = p.Decode
+	}
+	if p.Display != "" && !setFlags["display"] {
+		config.Display = p.Display
+	}
+
+	return nil
+}
+
+// loadProfile apply [default] section and named profile (when server is @name) of config file
+func loadProfile(server string) error {
+	path := configPath
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("MCCAT_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+
+	cf, err := loadConfigFile(expandHome(path), explicit)
+	if err != nil {
+		return err
+	}
+
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	if err := applyProfile(cf.Default, setFlags); err != nil {
+		return fmt.Errorf("wrong [default] section of config file: %s", err.Error())
+	}
+
+	if !strings.HasPrefix(server, "@") {
+		return nil
+	}
+
+	name := strings.TrimPrefix(server, "@")
+	p, ok := cf.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %s is not found in config file [%s]", name, path)
+	}
+	if len(p.Servers) == 0 {
+		return fmt.Errorf("profile %s has no servers", name)
+	}
+
+	if err := applyProfile(p, setFlags); err != nil {
+		return fmt.Errorf("wrong profile %s: %s", name, err.Error())
+	}
+
+	return nil
+}
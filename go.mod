@@ -1,8 +1,20 @@
 module github.com/heat1024/mccat
 
-go 1.12
+go 1.22
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/c-bata/go-prompt v0.2.5
+	github.com/golang/snappy v0.0.4
+	github.com/klauspost/compress v1.18.0
+)
+
+require (
+	github.com/mattn/go-colorable v0.1.7 // indirect
+	github.com/mattn/go-isatty v0.0.12 // indirect
+	github.com/mattn/go-runewidth v0.0.9 // indirect
+	github.com/mattn/go-tty v0.0.3 // indirect
+	github.com/pkg/term v1.1.0 // indirect
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
 	golang.org/x/sys v0.0.0-20200929083018-4d22bbb62b3c // indirect
 )
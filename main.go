@@ -1,25 +1,112 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	mccat "github.com/heat1024/mccat/memcache-cat"
 )
 
 var url string
+var config mccat.Config
+var historyFile string
+var commandArgs []string
+var configPath string
+var servers []string
+var authFile string
+var auth string
+var commands commandList
+var scriptFile string
+var stopOnError bool
+
+// commandList is repeatable -c flag value
+type commandList []string
+
+func (l *commandList) String() string {
+	return strings.Join(*l, "; ")
+}
+
+func (l *commandList) Set(cmd string) error {
+	*l = append(*l, cmd)
+	return nil
+}
 
 func init() {
-	url = "localhost:11211"
+	flag.StringVar(&config.Protocol, "protocol", "ascii", "")
+	flag.StringVar(&config.Username, "username", "", "")
+	flag.StringVar(&config.Password, "password", "", "")
+	flag.StringVar(&authFile, "auth-file", "", "")
+	flag.StringVar(&auth, "auth", "", "")
+	flag.Var(&commands, "c", "")
+	flag.Var(&commands, "command", "")
+	flag.StringVar(&scriptFile, "f", "", "")
+	flag.StringVar(&scriptFile, "file", "", "")
+	flag.BoolVar(&stopOnError, "stop-on-error", false, "")
+	flag.StringVar(&config.TLSCAFile, "tls-ca", "", "")
+	flag.StringVar(&config.TLSCertFile, "tls-cert", "", "")
+	flag.StringVar(&config.TLSKeyFile, "tls-key", "", "")
+	flag.StringVar(&config.TLSServerName, "tls-server-name", "", "")
+	flag.BoolVar(&config.TLSInsecureSkipVerify, "tls-insecure", false, "")
+	flag.DurationVar(&config.Timeout, "timeout", 0, "")
+	flag.StringVar(&historyFile, "history", os.Getenv("HOME")+"/.mccat_history", "")
+	flag.StringVar(&configPath, "config", "", "")
+	flag.BoolVar(&config.ReadOnly, "read-only", false, "")
+	flag.StringVar(&config.Output, "output", "", "")
+	flag.StringVar(&config.CompressionFlags, "compression-flags", "", "")
+	flag.BoolVar(&config.NoDecompress, "no-decompress", false, "")
+	flag.StringVar(&config.Decode, "decode", "", "")
+	flag.StringVar(&config.Display, "display", "", "")
+	flag.Usage = Usage
+	flag.Parse()
 
-	if len(os.Args) == 2 {
-		url = os.Args[1]
-	}
+	server := ""
+	args := flag.Args()
 
-	if url == "help" || url == "-h" {
-		Usage()
+	// mccat help [command]
+	if len(args) > 0 && args[0] == "help" {
+		if len(args) > 1 {
+			if err := mccat.PrintUsage(args[1]); err != nil {
+				os.Stderr.WriteString(fmt.Sprintf("%s\n", err.Error()))
+				os.Exit(1)
+			}
+		} else {
+			Usage()
+		}
 		os.Exit(0)
 	}
+
+	// first argument is server unless it is command (flags can be placed after server too)
+	if len(args) > 0 && !mccat.IsCommand(args[0]) {
+		server = args[0]
+
+		flag.CommandLine.Parse(args[1:])
+		args = flag.Args()
+	}
+
+	commandArgs = args
+
+	if err := loadProfile(server); err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", err.Error()))
+		os.Exit(1)
+	}
+
+	// server of argument is prior to servers of [default] section
+	if server != "" && !strings.HasPrefix(server, "@") {
+		servers = []string{server}
+	}
+	if len(servers) == 0 {
+		servers = []string{"localhost:11211"}
+	}
+	url = servers[0]
+
+	if err := loadCredentials(); err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("cannot load credentials: %s\n", err.Error()))
+		os.Exit(1)
+	}
 }
 
 // Usage show simple manual
@@ -28,19 +115,133 @@ func Usage() {
 	fmt.Println("--------------------------------------------------------------------")
 	fmt.Println("- when connect to tcp server (default)")
 	fmt.Println("   $ mccat [tcp://]URL:PORT (default : localhost:11211)")
+	fmt.Println("- when connect to tls server")
+	fmt.Println("   $ mccat tls://URL:PORT")
 	fmt.Println("- when connect to unix socket")
 	fmt.Println("   $ mccat [unix://]PATH")
+	fmt.Println("- when connect with profile of config file (~/.config/mccat/config.toml)")
+	fmt.Println("   $ mccat @profile")
+	fmt.Println("- when connect with options")
+	fmt.Println("   $ mccat [options] URL")
+	fmt.Println("- when run single command and exit (same as console command)")
+	fmt.Println("   $ mccat [options] [URL] command [args...]")
+	fmt.Println("   $ mccat [URL] command --help (show manual of command)")
+	fmt.Println("- when run commands without console (exit status is 1 when command failed)")
+	fmt.Println("   $ mccat -c \"get key\" [-c \"command\" ...] URL")
+	fmt.Println("- when run script file or commands from stdin")
+	fmt.Println("   $ mccat -f script.mccat URL")
+	fmt.Println("   $ cat commands.txt | mccat URL")
 	fmt.Println()
+	fmt.Println("  --command [-c] command    : run command and exit (can be repeated, values are printed as raw bytes)")
+	fmt.Println("  --file [-f] path          : run commands in script file and exit")
+	fmt.Println("  --stop-on-error           : stop script at first failed command")
+	fmt.Println("  --protocol ascii|binary   : protocol to communicate with server (default : ascii)")
+	fmt.Println("  --auth user:pass          : username and password for authentication")
+	fmt.Println("  --username user           : username for authentication (env : MCCAT_USERNAME)")
+	fmt.Println("  --password pass           : password for authentication (env : MCCAT_PASSWORD)")
+	fmt.Println("  --auth-file path          : file contains user:pass line (env : MCCAT_AUTH_FILE)")
+	fmt.Println("                              (binary protocol uses SASL PLAIN, ascii protocol uses -Y authfile token)")
+	fmt.Println("  --tls-ca path             : CA bundle file for verify server certificate")
+	fmt.Println("  --tls-cert path           : client certificate file")
+	fmt.Println("  --tls-key path            : client private key file")
+	fmt.Println("  --tls-server-name name    : server name for SNI and verification (default : host of URL)")
+	fmt.Println("  --tls-insecure            : skip verify server certificate")
+	fmt.Println("  --timeout duration        : limit of connecting and each request (ex: 500ms, 3s. default : no limit)")
+	fmt.Println("  --read-only               : reject commands which modify items")
+	fmt.Println("  --output format           : format of results (table, raw, json, jsonl, csv, tsv. default : table)")
+	fmt.Println("  --compression-flags list  : flag bits of compressed value (pymemcache, php, spymemcached or bit:codec)")
+	fmt.Println("  --no-decompress           : show compressed values as is (gzip, zlib, zstd, snappy, lz4 are decompressed)")
+	fmt.Println("  --decode format           : decode serialized values (auto, json, php, igbinary, pickle, java, cbor, msgpack)")
+	fmt.Println("  --display mode            : display mode of values (auto, raw, hex, escaped. default : auto)")
+	fmt.Println("  --config path             : config file (env : MCCAT_CONFIG, default : ~/.config/mccat/config.toml)")
+	fmt.Println("  --history path            : command history file (default : ~/.mccat_history, empty is not store history)")
 	fmt.Println("  --help [-h]               : show usage")
+	fmt.Println()
+	mccat.PrintUsage("")
+}
+
+// loadCredentials fill empty username / password from environments and auth file
+// (priority is flags > environments > auth file)
+func loadCredentials() error {
+	if auth != "" {
+		cred := strings.SplitN(auth, ":", 2)
+		if len(cred) != 2 {
+			return fmt.Errorf("--auth must be user:pass format")
+		}
+
+		config.Username, config.Password = cred[0], cred[1]
+	}
+
+	if config.Username == "" {
+		config.Username = os.Getenv("MCCAT_USERNAME")
+	}
+	if config.Password == "" {
+		config.Password = os.Getenv("MCCAT_PASSWORD")
+	}
+	if authFile == "" {
+		authFile = os.Getenv("MCCAT_AUTH_FILE")
+	}
+
+	if authFile == "" || (config.Username != "" && config.Password != "") {
+		return nil
+	}
+
+	buff, err := ioutil.ReadFile(authFile)
+	if err != nil {
+		return err
+	}
+
+	// same format with memcached -Y authfile (first user:pass line is used)
+	for _, line := range strings.Split(string(buff), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cred := strings.SplitN(line, ":", 2)
+		if len(cred) != 2 {
+			return fmt.Errorf("auth file %s must be user:pass format", authFile)
+		}
+
+		if config.Username == "" {
+			config.Username = cred[0]
+		}
+		if config.Password == "" {
+			config.Password = cred[1]
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("auth file %s has no credentials", authFile)
 }
 
 func main() {
-	historyFile := os.Getenv("HOME") + "/.mccat_history"
+	if len(commands) > 0 || len(commandArgs) > 0 {
+		os.Exit(runCommands())
+	}
+
+	if scriptFile != "" {
+		f, err := os.Open(scriptFile)
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("cannot open script file [%s]: %s\n", scriptFile, err.Error()))
+
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		os.Exit(runBatch(f))
+	}
+
+	// run commands from stdin when it is not terminal (pipe or redirect)
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		os.Exit(runBatch(os.Stdin))
+	}
 
 	// connect to memcached server
-	fmt.Printf("connect to memcached server [%s]\n", url)
+	fmt.Printf("connect to memcached server [%s]\n", strings.Join(servers, ", "))
 
-	nc, err := mccat.New(url, historyFile)
+	nc, err := connect(historyFile)
 	if err != nil {
 		os.Stderr.WriteString(fmt.Sprintf("cannot connect to server [%s]: %s\n", url, err.Error()))
 
@@ -48,7 +249,100 @@ func main() {
 	}
 	defer nc.Close(false)
 
+	if version, cipher, ok := nc.TLSConnectionState(); ok {
+		fmt.Printf("TLS connection established (%s, %s)\n", version, cipher)
+	}
+
 	nc.Start()
 
 	os.Exit(0)
 }
+
+// connect try servers in order and return client of first connected server
+func connect(history string) (*mccat.Client, error) {
+	var err error
+
+	for _, server := range servers {
+		var nc *mccat.Client
+
+		nc, err = mccat.NewWithConfig(server, history, config)
+		if err == nil {
+			url = server
+			return nc, nil
+		}
+
+		if len(servers) > 1 {
+			os.Stderr.WriteString(fmt.Sprintf("cannot connect to server [%s]: %s\n", server, err.Error()))
+		}
+	}
+
+	return nil, err
+}
+
+// runCommands run -c commands and command of arguments in order and return exit status
+// (stop at first failed command)
+func runCommands() int {
+	nc, err := connect("")
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("cannot connect to server [%s]: %s\n", url, err.Error()))
+
+		return 1
+	}
+	defer nc.Close(true)
+
+	for _, cmd := range commands {
+		if err := nc.Execute(cmd); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("%s: %s\n", cmd, err.Error()))
+
+			return 1
+		}
+	}
+
+	// command of arguments is already split by shell
+	if len(commandArgs) > 0 {
+		if err := nc.ExecuteArgs(commandArgs); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("%s: %s\n", strings.Join(commandArgs, " "), err.Error()))
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// runBatch run script lines and show result of each line, then return exit status
+func runBatch(r io.Reader) int {
+	nc, err := connect("")
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("cannot connect to server [%s]: %s\n", url, err.Error()))
+
+		return 1
+	}
+	defer nc.Close(true)
+
+	results, err := nc.RunBatch(r, stopOnError)
+
+	failed := 0
+	os.Stderr.WriteString("--------------------------------------------------------------------\n")
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+			os.Stderr.WriteString(fmt.Sprintf("line %4d : FAIL : %s (%s)\n", res.Line, res.Command, res.Err.Error()))
+		} else {
+			os.Stderr.WriteString(fmt.Sprintf("line %4d : OK   : %s\n", res.Line, res.Command))
+		}
+	}
+	os.Stderr.WriteString(fmt.Sprintf("%d commands, %d succeeded, %d failed\n", len(results), len(results)-failed, failed))
+
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", err.Error()))
+
+		return 1
+	}
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
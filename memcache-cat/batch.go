@@ -0,0 +1,80 @@
+package mccat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// BatchResult is result of one command line executed by RunBatch
+type BatchResult struct {
+	Line    int
+	Command string
+	Err     error
+}
+
+// RunBatch read command lines from r and execute them in order.
+// empty lines and lines start with # are skipped, and exit or quit stops batch.
+// value of storage commands is taken from inline value, following line or heredoc.
+func (c *Client) RunBatch(r io.Reader, stopOnError bool) ([]BatchResult, error) {
+	var results []BatchResult
+
+	batch := c.batch
+	c.batch = true
+	defer func() { c.batch = batch }()
+
+	// values which are not inline (following line, heredoc) are read from script too
+	stdin := c.input
+	c.input = newLineReader(r)
+	defer func() { c.input = stdin }()
+
+	for {
+		line, err := c.input.readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed on reading batch line %d: %s", c.input.lines+1, err.Error())
+		}
+		lineNo := c.input.lines
+
+		cmd := strings.TrimSpace(line)
+		if cmd == "" || strings.HasPrefix(cmd, "#") {
+			continue
+		}
+
+		// stop batch
+		if strings.HasPrefix(strings.ToLower(cmd), "exit") || strings.HasPrefix(strings.ToLower(cmd), "quit") {
+			break
+		}
+
+		res := BatchResult{Line: lineNo, Command: cmd}
+
+		cmds, err := parseCmd(cmd)
+		if err != nil {
+			res.Err = err
+		} else if cmds != nil {
+			res.Err = c.Run(cmds)
+		}
+
+		results = append(results, res)
+
+		if res.Err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("line %d [%s]: %s\n", res.Line, res.Command, res.Err.Error()))
+
+			if stopOnError {
+				break
+			}
+		}
+
+		// reconnect when connection is lost while executing command
+		if c.broken {
+			if err := c.Reconnect(); err != nil {
+				return results, fmt.Errorf("failed to reconnect: %s", err.Error())
+			}
+		}
+	}
+
+	return results, nil
+}
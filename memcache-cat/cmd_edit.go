@@ -0,0 +1,126 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+)
+
+// maxRelativeTTL is max ttl which memcached treat as relative seconds (larger ttl is unix time)
+const maxRelativeTTL = 60 * 60 * 24 * 30
+
+// Edit fetch value of key by gets, open it in editor and write it back by cas
+// with same flags. ttl < 0 means keeping remaining ttl of item (ascii protocol only)
+func (c *Client) Edit(key string, ttl int) error {
+	item, err := c.Gets(key)
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %s", key, err.Error())
+	}
+
+	if ttl < 0 {
+		ttl, err = c.remainingTTL(key)
+		if err != nil {
+			return fmt.Errorf("cannot get remaining ttl of key %s (specify ttl like edit key ttl): %s", key, err.Error())
+		}
+	}
+
+	// JSON value is pretty printed for edit, and compacted again when it was compact
+	isJSON := json.Valid(item.Value)
+	initial := item.Value
+	suffix := ".txt"
+
+	if isJSON {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, item.Value, "", "  "); err == nil {
+			initial = append(pretty.Bytes(), '\n')
+			suffix = ".json"
+		}
+	}
+
+	value, err := c.editorValue(initial, suffix)
+	if err != nil {
+		return err
+	}
+
+	if isJSON {
+		if !json.Valid(value) {
+			return fmt.Errorf("edited value is not valid JSON. key %s is not changed", key)
+		}
+
+		if !bytes.Contains(item.Value, []byte("\n")) {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, value); err == nil {
+				value = compact.Bytes()
+			}
+		}
+	}
+
+	if bytes.Equal(value, item.Value) {
+		c.printResult(fmt.Sprintf("key %s is not changed", key),
+			field{"command", "edit"}, field{"key", key}, field{"status", "not_changed"})
+		return nil
+	}
+
+	edited := &Item{
+		Key:   key,
+		Value: value,
+		Flags: item.Flags,
+		TTL:   ttl,
+		CAS:   item.CAS,
+	}
+
+	if err := c.CompareAndSwap(edited); err != nil {
+		// keep edited value for retry
+		f, ferr := ioutil.TempFile("", "mccat-edit-*"+suffix)
+		if ferr == nil {
+			f.Write(value)
+			f.Close()
+			return fmt.Errorf("failed to write back key %s: %s (edited value is saved to %s)", key, err.Error(), f.Name())
+		}
+
+		return fmt.Errorf("failed to write back key %s: %s", key, err.Error())
+	}
+
+	c.printResult(fmt.Sprintf("key %s edited [flags: %d, ttl: %d, size: %d]", key, edited.Flags, edited.TTL, len(edited.Value)),
+		field{"command", "edit"}, field{"key", key}, field{"status", "stored"})
+
+	return nil
+}
+
+// remainingTTL return ttl for store item again with same expiration time
+// (0 when item never expire, unix time when remaining ttl is over 30 days)
+func (c *Client) remainingTTL(key string) (int, error) {
+	if c.proto.name() != protocolASCII {
+		return 0, fmt.Errorf("remaining ttl is only supported on ascii protocol")
+	}
+
+	res, err := c.MetaGet(key, MetaFlags{{Key: 't'}})
+	if err != nil {
+		return 0, err
+	}
+	if res.Status == MetaEnd || res.Status == MetaNotFound {
+		return 0, ErrCacheMiss
+	}
+
+	token, ok := res.Flags.Get('t')
+	if !ok {
+		return 0, fmt.Errorf("server did not return ttl")
+	}
+
+	ttl, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("got malformed ttl %s", token)
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+	if ttl > maxRelativeTTL {
+		return int(time.Now().Unix()) + ttl, nil
+	}
+
+	return ttl, nil
+}
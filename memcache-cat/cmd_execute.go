@@ -1,8 +1,8 @@
 package mccat
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -30,245 +30,299 @@ func convertTOHumanDigitNumber(num uint64) string {
 
 // Get search data by key and return by Item struct
 func (c *Client) Get(key string) (*Item, error) {
-	var value string
+	return c.proto.retrieve("get", key, 0)
+}
 
-	err := c.Write(fmt.Sprintf("get %s", key))
-	if err != nil {
-		return nil, err
-	}
+// GetMulti search data of keys at once and return them by key.
+// keys are sent by chunks of getMultiChunkSize, and cache missed keys are not included in result
+func (c *Client) GetMulti(keys []string) (map[string]*Item, error) {
+	items := make(map[string]*Item, len(keys))
 
-	for {
-		buff, err := c.Read()
-		if err != nil && err != io.EOF {
-			return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	for start := 0; start < len(keys); start += getMultiChunkSize {
+		chunk, err := c.proto.getMulti(keys[start:min(start+getMultiChunkSize, len(keys))])
+		if err != nil {
+			return nil, err
 		}
 
-		if buff == "END" {
-			break
+		for key, item := range chunk {
+			items[key] = item
 		}
-		if strings.Contains(buff, "ERROR") {
-			return nil, fmt.Errorf("got error on get data of key [%s] from memcached server", key)
-		}
-		if !strings.HasPrefix(buff, "VALUE") {
-			value = buff
-		}
-	}
-
-	if len(value) == 0 {
-		return nil, fmt.Errorf("got error! (cache missed)")
 	}
 
-	return &Item{Key: key, Value: value}, nil
+	return items, nil
 }
 
-// Store function stores key / value to memcached server by each commands
-func (c *Client) Store(cmds *cmds, ttl int, value string) error {
-	cmd := cmds.argv[0]
-	key := cmds.argv[1]
-	size := len(value)
+// Gets search data by key and return by Item struct with cas unique
+func (c *Client) Gets(key string) (*Item, error) {
+	return c.proto.retrieve("gets", key, 0)
+}
 
-	err := c.Write(fmt.Sprintf("%s %s 0 %d %d\r\n%s", cmd, key, ttl, size, value))
-	if err != nil {
-		return err
-	}
+// GetAndTouch search data by key and update its ttl at once
+func (c *Client) GetAndTouch(key string, ttl int) (*Item, error) {
+	return c.proto.retrieve("gat", key, ttl)
+}
 
-	buff, err := c.Read()
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
-	}
+// printItems retrieve keys and display them. cache missed keys are counted as failure in batch mode
+func (c *Client) printItems(cmd string, keys []string, ttl int, ops options) error {
+	missed := 0
 
-	if strings.HasPrefix(buff, "NOT_STORED") {
-		if cmd == "add" {
-			return fmt.Errorf("failed to %s: key exist", cmd)
+	// get multi keys at once (others are sent by each key for show its own error)
+	if cmd == "get" && len(keys) > 1 {
+		items, err := c.GetMulti(keys)
+		if err != nil {
+			return err
 		}
 
-		return fmt.Errorf("failed to %s: key does not exist", cmd)
+		for _, key := range keys {
+			if item, ok := items[key]; ok {
+				c.printItem(item, ops)
+			} else {
+				c.printMiss(key, ErrCacheMiss)
+				missed++
+			}
+		}
+	} else {
+		for _, key := range keys {
+			item, err := c.proto.retrieve(cmd, key, ttl)
+			if err != nil {
+				c.printMiss(key, err)
+				missed++
+				continue
+			}
 
+			c.printItem(item, ops)
+		}
 	}
 
-	if strings.Contains(buff, "ERROR") {
-		return fmt.Errorf("got error on %s value to memcached server", cmd)
+	if c.batch && missed > 0 {
+		return fmt.Errorf("failed to %s %d of %d keys", cmd, missed, len(keys))
 	}
 
 	return nil
 }
 
-// Del function delete data by key from memcached server
-func (c *Client) Del(key string) error {
-	err := c.Write(fmt.Sprintf("delete %s", key))
-	if err != nil {
-		return err
-	}
+// printItem display item. value is written as raw bytes in raw format.
+// compressed value is shown after decompressed, and serialized value is decoded by decoder
+func (c *Client) printItem(item *Item, ops options) {
+	c.out.write(c.itemRecord("", item, ops))
+}
 
-	buff, err := c.Read()
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
-	}
+// itemRecord make record of item for display. text is prefixed by prefix.
+// hex and escaped display modes change value of all formats, and auto changes only text of table format
+// (raw format keep bytes for pipe, and JSON encode them by base64)
+func (c *Client) itemRecord(prefix string, item *Item, ops options) *record {
+	item = c.displayItem(item, ops.decoder)
 
-	if strings.HasPrefix(buff, "NOT_FOUND") {
-		return fmt.Errorf("key %s not found", key)
+	mode := ops.display
+	if mode == "" {
+		mode = c.display
+	}
+	// decoded value is JSON text
+	if item.Serialization != "" {
+		mode = displayRaw
 	}
 
-	if strings.Contains(buff, "ERROR") {
-		return fmt.Errorf("got error on delete key %s from memcached server", key)
+	text := fmt.Sprintf("%s%s %s :%s", prefix, item.Key, itemAttributes(item), valueText(item.Value, mode))
+
+	if mode == displayHex || mode == displayEscaped {
+		item.Value = displayValue(item.Value, mode)
 	}
 
-	return nil
+	return &record{
+		text:     text,
+		fields:   itemFields(item),
+		value:    item.Value,
+		hasValue: true,
+	}
 }
 
-// IncrDecr function increment or decrement numeric data
-func (c *Client) IncrDecr(cmds *cmds) (string, error) {
-	cmd := cmds.argv[0]
-	key := cmds.argv[1]
-	value := cmds.argv[2]
+// displayItem return copy of item which value is decompressed and decoded for display.
+// decoder is name of serialization format (auto detects format, and config is used when it is empty)
+func (c *Client) displayItem(item *Item, decoder string) *Item {
+	shown := *item
 
-	err := c.Write(fmt.Sprintf("%s %s %s", cmd, key, value))
-	if err != nil {
-		return "", err
+	if !c.config.NoDecompress {
+		shown.Value, shown.Compression = c.decompress(item.Value, item.Flags)
 	}
 
-	buff, err := c.Read()
-	if err != nil && err != io.EOF {
-		return "", fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	if decoder == "" {
+		decoder = c.config.Decode
 	}
 
-	if strings.HasPrefix(buff, "NOT_FOUND") {
-		return "", fmt.Errorf("key %s not found", key)
-	}
-	if strings.Contains(buff, "ERROR") {
-		return "", fmt.Errorf("cannot increment or decrement non-numeric value")
+	value, format, err := decodeValue(shown.Value, decoder)
+	if err != nil {
+		c.printError(fmt.Sprintf("%s : %s", item.Key, err.Error()))
 	}
+	shown.Value, shown.Serialization = value, format
 
-	return buff, nil
+	return &shown
 }
 
-// GetAll return all key/value data in memcached server
-func (c *Client) GetAll(ops options) error {
-	var SlabIDs []int
-	var keyCounts uint64
-	var err error
+// printMiss display error of key
+func (c *Client) printMiss(key string, err error) {
+	c.printError(fmt.Sprintf("%s : %s", key, err.Error()))
+}
 
-	SlabIDs, keyCounts, err = c.getSlabDataAndKeyCount()
-	if err != nil {
-		return fmt.Errorf("cannot get slab data from memcached server: %s", err.Error())
+// itemAttributes return flags, cas, size, compression and serialization of item for display
+func itemAttributes(item *Item) string {
+	attrs := fmt.Sprintf("flags: %d", item.Flags)
+	if item.CAS != 0 {
+		attrs += fmt.Sprintf(", cas: %d", item.CAS)
 	}
-
-	if ops.countOnly {
-		fmt.Printf("Key counts: %s\n", convertTOHumanDigitNumber(keyCounts))
-	} else {
-		err = c.getKeyListFromCachedump(SlabIDs, ops)
-		if err != nil {
-			return err
-		}
+	attrs += fmt.Sprintf(", size: %d", item.Size)
+	if item.Compression != "" {
+		attrs += fmt.Sprintf(", compression: %s", item.Compression)
+	}
+	if item.Serialization != "" {
+		attrs += fmt.Sprintf(", serialization: %s", item.Serialization)
 	}
 
-	return nil
+	return "[" + attrs + "]"
 }
 
-// FlushAll delete all exist keys
-func (c *Client) FlushAll() error {
-	err := c.Write("flush_all")
-	if err != nil {
-		return err
+// keyInfoRecord make record of listed key with its metadata (names are same as metadump).
+// raw format writes only key for pipe, and table omits last access, cas and fetch unknown by cachedump
+func keyInfoRecord(info *KeyInfo) *record {
+	fetch := "no"
+	if info.Fetched {
+		fetch = "yes"
 	}
 
-	buff, err := c.Read()
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	attrs := fmt.Sprintf("exp: %d", info.Expiration)
+	if info.LastAccess != 0 || info.CAS != 0 {
+		attrs += fmt.Sprintf(", la: %d, cas: %d, fetch: %s", info.LastAccess, info.CAS, fetch)
 	}
+	attrs += fmt.Sprintf(", cls: %d, size: %d", info.Slab, info.Size)
+
+	return &record{
+		text: fmt.Sprintf("  - %s [%s]", info.Key, attrs),
+		fields: []field{
+			{"key", info.Key},
+			{"exp", info.Expiration},
+			{"la", info.LastAccess},
+			{"cas", info.CAS},
+			{"fetch", fetch},
+			{"cls", info.Slab},
+			{"size", info.Size},
+		},
+		value:    []byte(info.Key + "\n"),
+		hasValue: true,
+	}
+}
 
-	if strings.Contains(buff, "ERROR") {
-		return fmt.Errorf("got error on flush all keys from memcached server")
+// Store function stores key / value to memcached server by each commands
+func (c *Client) Store(cmds *cmds, ttl int, value []byte) error {
+	item := &Item{
+		Key:   cmds.argv[1],
+		Value: value,
+		Flags: cmds.ops.flags,
+		TTL:   ttl,
 	}
 
-	return nil
+	return c.proto.store(cmds.argv[0], item)
 }
 
-func checkKeyMatch(key string, ops options) bool {
-	var matchName bool
-	var matchGrep bool
+// CompareAndSwap stores item only when it was not modified since item fetched by Gets
+func (c *Client) CompareAndSwap(item *Item) error {
+	return c.proto.store("cas", item)
+}
 
-	ns := strings.SplitN(key, ":", 2)[0]
+// Touch update ttl of exist key without rewrite value
+func (c *Client) Touch(key string, ttl int) error {
+	return c.proto.touch(key, ttl)
+}
 
-	matchName = true
-	matchGrep = true
+// Del function delete data by key from memcached server
+func (c *Client) Del(key string) error {
+	return c.proto.delete(key)
+}
 
-	// if namespace defined, compare with namespace
-	if len(ops.namespace) > 0 && ns != ops.namespace {
-		matchName = false
-	}
-	// if vnamespace defined, compare with namespace
-	if len(ops.vnamespace) > 0 && ns == ops.vnamespace {
-		matchName = false
+// IncrDecr function increment or decrement numeric data
+func (c *Client) IncrDecr(cmds *cmds) (string, error) {
+	delta, err := strconv.ParseUint(cmds.argv[2], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("numeric must be unsigned integer: %s", cmds.argv[2])
 	}
-	// if grep word defined, check about key contains words
-	if len(ops.grep) > 0 && !strings.Contains(key, ops.grep) {
-		matchGrep = false
+
+	value, err := c.proto.incrDecr(cmds.argv[0], cmds.argv[1], delta)
+	if err != nil {
+		return "", err
 	}
-	// if vgrep word defined, check about key contains words
-	if len(ops.vgrep) > 0 && strings.Contains(key, ops.vgrep) {
-		matchGrep = false
+
+	return strconv.FormatUint(value, 10), nil
+}
+
+// Version return version of memcached server
+func (c *Client) Version() (string, error) {
+	return c.proto.version()
+}
+
+// GetAll return all key/value data in memcached server
+func (c *Client) GetAll(ops options) error {
+	if ops.countOnly {
+		_, keyCounts, err := c.getSlabDataAndKeyCount()
+		if err != nil {
+			return fmt.Errorf("cannot get slab data from memcached server: %s", err.Error())
+		}
+
+		c.printResult(fmt.Sprintf("Key counts: %s", convertTOHumanDigitNumber(keyCounts)), field{"key_counts", keyCounts})
+
+		return nil
 	}
 
-	return matchName && matchGrep
+	return c.getKeyList(ops)
+}
+
+// FlushAll delete all exist keys
+func (c *Client) FlushAll() error {
+	return c.proto.flushAll()
 }
 
 func (c *Client) getSlabDataAndKeyCount() ([]int, uint64, error) {
 	var slabIDs []int
 	keyCounts := uint64(0)
 
-	err := c.Write("stats items")
+	stats, err := c.proto.stats("items")
 	if err != nil {
 		return nil, keyCounts, err
-
 	}
 
-	for {
-		buff, err := c.Read()
-		if err != nil && err != io.EOF {
-			return nil, keyCounts, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	// items:<slab id>:number <count>
+	for _, st := range stats {
+		s := strings.Split(st.name, ":")
+		if len(s) != 3 || s[2] != "number" {
+			continue
 		}
 
-		if strings.HasPrefix(buff, "END") {
-			break
-		}
-		if strings.Contains(buff, "ERROR") {
-			return nil, keyCounts, fmt.Errorf("got error on reading response from memcached server")
+		slabID, err := strconv.Atoi(s[1])
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("got error on parse slave ID: %s", err.Error()))
+			os.Exit(1)
 		}
-		if strings.HasPrefix(buff, "STAT") {
-			s := strings.Split(buff, ":")
-			if slabID, err := strconv.Atoi(s[1]); err != nil {
-				os.Stderr.WriteString(fmt.Sprintf("got error on parse slave ID: %s", err.Error()))
-				os.Exit(1)
-			} else {
-				numberString := fmt.Sprintf("STAT items:%d:number ", slabID)
-				if strings.HasPrefix(buff, numberString) {
-					slabIDs = append(slabIDs, slabID)
-
-					f := strings.Fields(buff)
-					count, err := strconv.ParseUint(f[2], 10, 64)
-					if err != nil {
-						os.Stderr.WriteString(fmt.Sprintf("got error on get slab %d's object count %s: %s", slabID, f[2], err.Error()))
-						continue
-					} else {
-						keyCounts += count
-					}
-				}
-			}
+
+		slabIDs = append(slabIDs, slabID)
+
+		count, err := strconv.ParseUint(strings.TrimSpace(st.value), 10, 64)
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("got error on get slab %d's object count %s: %s", slabID, st.value, err.Error()))
+			continue
 		}
+
+		keyCounts += count
 	}
 
 	return slabIDs, keyCounts, nil
 }
 
-func (c *Client) getKeyListFromCachedump(SlabIDs []int, ops options) error {
+// getKeyList print keys (and values in verbose mode) which match with options
+func (c *Client) getKeyList(ops options) error {
 	var newClient *Client = nil
 	var silent bool = true
 	var err error
 
 	// set new connection when getall with verbose option
 	if !ops.keyOnly {
-		newClient, err = New(c.url, "")
+		newClient, err = NewWithConfig(c.url, "", c.config)
 		if err != nil {
 			os.Stderr.WriteString(fmt.Sprintf("cannot connect to server [%s] for get value\n", c.url))
 			newClient = nil
@@ -276,44 +330,65 @@ func (c *Client) getKeyListFromCachedump(SlabIDs []int, ops options) error {
 		defer newClient.Close(silent)
 	}
 
-	for _, slab := range SlabIDs {
-		err := c.Write(fmt.Sprintf("stats cachedump %d 0", slab))
+	var keys []string
+
+	// keys which cannot be saved to out dir are reported at the end without stop dump
+	saveFailed := 0
+
+	// get values of matched keys by multi get
+	printValues := func() error {
+		defer func() { keys = keys[:0] }()
+
+		if newClient == nil {
+			return nil
+		}
+
+		values, err := newClient.GetMulti(keys)
 		if err != nil {
 			return err
 		}
 
-		for {
-			buff, err := c.Read()
-			if err != nil && err != io.EOF {
-				return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
-			}
-
-			if strings.HasPrefix(buff, "END") {
-				break
-			}
-			if strings.Contains(buff, "ERROR") {
-				return fmt.Errorf("got error on reading response from memcached server")
-			}
-			if strings.HasPrefix(buff, "ITEM") {
-				key := strings.Split(buff, " ")[1]
-
-				// if key match with option, print it
-				if checkKeyMatch(key, ops) {
-					if ops.keyOnly {
-						fmt.Printf("  - %s\n", key)
-					} else {
-						if newClient != nil {
-							item, err := newClient.Get(key)
-							if err != nil {
-								fmt.Printf("  - %s : %s\n", key, err.Error())
-							} else {
-								fmt.Printf("  - %s : %s\n", item.Key, item.Value)
-							}
-						}
-					}
+		for _, key := range keys {
+			item, ok := values[key]
+			if !ok {
+				c.printError(fmt.Sprintf("  - %s : %s", key, ErrCacheMiss.Error()))
+			} else if ops.outDir != "" {
+				if err := c.saveItemToDir(item, ops.outDir); err != nil {
+					c.printError(fmt.Sprintf("  - %s", err.Error()))
+					saveFailed++
 				}
+			} else {
+				c.out.write(c.itemRecord("  - ", item, ops))
 			}
 		}
+
+		return nil
+	}
+
+	err = c.Keys(context.Background(), ops.filter, func(info *KeyInfo) error {
+		if ops.keyOnly {
+			c.out.write(keyInfoRecord(info))
+
+			return nil
+		}
+
+		keys = append(keys, info.Key)
+		if len(keys) < getMultiChunkSize {
+			return nil
+		}
+
+		return printValues()
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := printValues(); err != nil {
+		return err
+	}
+
+	if saveFailed > 0 {
+		return fmt.Errorf("failed to save %d keys to %s", saveFailed, ops.outDir)
 	}
 
 	return nil
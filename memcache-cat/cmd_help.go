@@ -0,0 +1,159 @@
+package mccat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commandDoc is manual of one command. first name is used in Run, others are aliases
+type commandDoc struct {
+	names       []string
+	usage       string
+	description string
+}
+
+var commandDocs = []commandDoc{
+	{[]string{"get"}, "get key [key2] ... [-d format] [-x] [--out file | > file]", "Get data from server"},
+	{[]string{"gets"}, "gets key [key2] ... [-d format] [-x] [--out file | > file]", "Get data with cas unique from server"},
+	{[]string{"gat"}, "gat ttl key [key2] ... [-d format] [-x] [--out file | > file]", "Get data and update ttl"},
+	{[]string{"gats"}, "gats ttl key [key2] ... [-d format] [-x] [--out file | > file]", "Get data with cas unique and update ttl"},
+	{[]string{"touch"}, "touch key ttl", "Update ttl without rewrite data"},
+	{[]string{"set"}, "set key ttl [options] [value|<<EOF]", "Set data (overwrite when exist)"},
+	{[]string{"add"}, "add key ttl [options] [value|<<EOF]", "Add new data (error when key exist)"},
+	{[]string{"cas"}, "cas key ttl cas_unique [options] [value|<<EOF]", "Set data only when not modified since gets"},
+	{[]string{"append"}, "append key ttl [options] [value|<<EOF]", "Append data from exist data"},
+	{[]string{"prepend"}, "prepend key ttl [options] [value|<<EOF]", "Prepend data from exist data"},
+	{[]string{"replace"}, "replace key ttl [options] [value|<<EOF]", "Replace data from exist data"},
+	{[]string{"edit"}, "edit key [ttl]", "Edit value with $EDITOR and write back by cas (keep flags and remaining ttl)"},
+	{[]string{"incr", "increase"}, "incr[increase] key number", "Increase numeric value"},
+	{[]string{"decr", "decrease"}, "decr[decrease] key number", "Decrease numeric value"},
+	{[]string{"del", "delete", "rm", "remove"}, "del[delete|rm|remove] key [key2] [key3] ...", "Remove key item from server"},
+	{[]string{"mg"}, "mg key [flags]", "Meta get (ex: mg key v t f c)"},
+	{[]string{"ms"}, "ms key [flags]", "Meta set (ex: ms key T3600 F5)"},
+	{[]string{"md"}, "md key [flags]", "Meta delete (ex: md key q)"},
+	{[]string{"ma"}, "ma key [flags]", "Meta arithmetic (ex: ma key MI D5 v)"},
+	{[]string{"mn"}, "mn", "Meta no-op"},
+	{[]string{"me"}, "me key [b]", "Meta debug (show item attributes)"},
+	{[]string{"keycounts", "key_counts"}, "key_counts", "Get key counts"},
+	{[]string{"getall", "get_all"}, "get_all [filters] [-v] [-d format] [-x] [--out-dir dir]", "Get all items from server (can filter keys by name, size, ttl and last access)"},
+	{[]string{"flushall", "flush_all", "flush"}, "flush_all", "Delete all items"},
+	{[]string{"stats"}, "stats [items|slabs|settings|conns|...]", "Show statistics of memcached server"},
+	{[]string{"version"}, "version", "Show memcached server version"},
+	{[]string{"output"}, "output [table|raw|json|jsonl|csv|tsv]", "Show or change output format of command results"},
+	{[]string{"display"}, "display [auto|raw|hex|escaped]", "Show or change display mode of values (auto shows hex dump of binary value)"},
+	{[]string{"help"}, "help [command]", "Show usage"},
+}
+
+// valueOptions are options of storage commands which take value
+var valueOptions = []string{
+	"-e, --editor                : input value with $EDITOR",
+	"--file path                 : read value from file as is (- is whole stdin)",
+	"--                          : take rest of line as value even if it looks like option",
+}
+
+// storeOptions are options of storage commands which store new item
+var storeOptions = append([]string{
+	"-f, --flags n               : client flags (32bit unsigned integer)",
+	"-z, --compress codec        : compress value (gzip, zlib, zstd, snappy, lz4)",
+}, valueOptions...)
+
+// commandOptions are options of command shown by help of each command
+var commandOptions = map[string][]string{
+	"set":     storeOptions,
+	"add":     storeOptions,
+	"cas":     storeOptions,
+	"replace": storeOptions,
+	"append":  valueOptions,
+	"prepend": valueOptions,
+	"getall": {
+		"-n, --name namespace        : namespace (part of key before separator)",
+		"-vn, --vname namespace      : exclude namespace",
+		"-g, --grep word             : word in key (can be repeated, and all words must be matched)",
+		"--or                        : match any of --grep words",
+		"-vg, --vgrep word           : exclude keys contain word (can be repeated)",
+		"-r, --regex expr            : regular expression matched with key",
+		"--glob pattern              : shell pattern matched with whole key (*, ? and [...])",
+		"--min-size, --max-size n    : range of item size (bytes. value size by stats cachedump)",
+		"--expires-within duration   : keys expire within duration (ex: 300, 5m, 1h)",
+		"--no-expiry                 : keys never expire",
+		"--idle-longer-than duration : keys not accessed longer than duration (lru_crawler metadump only)",
+		"--slab id                   : keys in slab class",
+		"-v, --verbose               : show values of keys",
+		"-d, --decode format         : decode serialized values (implies --verbose)",
+		"-x, --hex, --escaped        : show values as hex dump or Go quoted string (implies --verbose)",
+		"--out-dir dir               : save values to files in directory (implies --verbose)",
+	},
+}
+
+func findCommandDoc(name string) *commandDoc {
+	name = strings.ToLower(name)
+
+	for i := range commandDocs {
+		for _, n := range commandDocs[i].names {
+			if n == name {
+				return &commandDocs[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *commandDoc) print() {
+	fmt.Printf("> %-70s: %s\n", d.usage, d.description)
+}
+
+func usage() {
+	fmt.Println("Command list")
+
+	for i := range commandDocs {
+		commandDocs[i].print()
+	}
+}
+
+// commandUsage show manual of one command (show whole usage when command is unknown)
+func commandUsage(name string) {
+	d := findCommandDoc(name)
+	if d == nil {
+		usage()
+		return
+	}
+
+	d.print()
+
+	// show aliases which are not written in usage
+	var aliases []string
+	for _, n := range d.names {
+		if !strings.Contains(strings.Fields(d.usage)[0], n) {
+			aliases = append(aliases, n)
+		}
+	}
+	if len(aliases) > 0 {
+		fmt.Printf("  (aliases: %s)\n", strings.Join(aliases, ", "))
+	}
+
+	for _, o := range commandOptions[d.names[0]] {
+		fmt.Printf("  %s\n", o)
+	}
+}
+
+// IsCommand check name is mccat command (include aliases)
+func IsCommand(name string) bool {
+	return findCommandDoc(name) != nil
+}
+
+// PrintUsage show manual of command. whole command list is shown when command is empty
+func PrintUsage(command string) error {
+	if command == "" {
+		usage()
+		return nil
+	}
+
+	if !IsCommand(command) {
+		return fmt.Errorf("wrong command %s", command)
+	}
+
+	commandUsage(command)
+
+	return nil
+}
@@ -0,0 +1,404 @@
+package mccat
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// meta command response codes
+const (
+	MetaValue    = "VA"
+	MetaHeader   = "HD"
+	MetaEnd      = "EN"
+	MetaNotStore = "NS"
+	MetaExists   = "EX"
+	MetaNotFound = "NF"
+	MetaNoop     = "MN"
+	MetaDebug    = "ME"
+)
+
+var metaStatusDescriptions = map[string]string{
+	MetaValue:    "value follows",
+	MetaHeader:   "success",
+	MetaEnd:      "cache miss",
+	MetaNotStore: "not stored",
+	MetaExists:   "cas mismatch or item exists",
+	MetaNotFound: "not found",
+	MetaNoop:     "quiet mode, nothing returned",
+	MetaDebug:    "debug information",
+}
+
+var metaFlagDescriptions = map[byte]string{
+	'b': "base64 key",
+	'c': "cas",
+	'f': "client flags",
+	'h': "hit before",
+	'k': "key",
+	'l': "last access (sec)",
+	'O': "opaque",
+	's': "size",
+	't': "ttl remaining (sec)",
+	'W': "win (recache this item)",
+	'X': "stale",
+	'Z': "win token already sent",
+}
+
+// MetaFlag is single flag of meta command (flag character and optional token)
+type MetaFlag struct {
+	Key   byte
+	Token string
+}
+
+// MetaFlags is list of meta command flags
+type MetaFlags []MetaFlag
+
+// MetaResult is response of meta command
+type MetaResult struct {
+	Status string
+	Flags  MetaFlags
+	Value  []byte
+	// Debug is key=value attributes returned by me command
+	Debug map[string]string
+}
+
+// ParseMetaFlags parse flag tokens like "v", "t", "T30", "Oabc"
+func ParseMetaFlags(tokens []string) (MetaFlags, error) {
+	var flags MetaFlags
+
+	for _, t := range tokens {
+		if len(t) == 0 {
+			continue
+		}
+
+		k := t[0]
+		if !(k >= 'a' && k <= 'z') && !(k >= 'A' && k <= 'Z') {
+			return nil, fmt.Errorf("wrong meta flag: %s", t)
+		}
+
+		flags = append(flags, MetaFlag{Key: k, Token: t[1:]})
+	}
+
+	return flags, nil
+}
+
+// String encode flags to meta command format
+func (f MetaFlags) String() string {
+	tokens := make([]string, 0, len(f))
+
+	for _, flag := range f {
+		tokens = append(tokens, string(flag.Key)+flag.Token)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// Has check flag exist
+func (f MetaFlags) Has(k byte) bool {
+	_, ok := f.Get(k)
+
+	return ok
+}
+
+// Get return token of flag
+func (f MetaFlags) Get(k byte) (string, bool) {
+	for _, flag := range f {
+		if flag.Key == k {
+			return flag.Token, true
+		}
+	}
+
+	return "", false
+}
+
+// describe return human readable flag list
+func (f MetaFlags) describe() string {
+	tokens := make([]string, 0, len(f))
+
+	for _, flag := range f {
+		desc, ok := metaFlagDescriptions[flag.Key]
+		if !ok {
+			desc = "unknown"
+		}
+
+		if len(flag.Token) > 0 {
+			tokens = append(tokens, fmt.Sprintf("%c=%s (%s)", flag.Key, flag.Token, desc))
+		} else {
+			tokens = append(tokens, fmt.Sprintf("%c (%s)", flag.Key, desc))
+		}
+	}
+
+	return strings.Join(tokens, ", ")
+}
+
+// encodeMetaKey encode key to base64 when b flag defined.
+// key without b flag must be valid key of text protocol
+func encodeMetaKey(key string, flags MetaFlags) (string, error) {
+	if !flags.Has('b') {
+		return key, checkKey(key)
+	}
+
+	if key == "" || len(key) > maxKeyLength {
+		return "", fmt.Errorf("key must be 1 to %d bytes: %d bytes", maxKeyLength, len(key))
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(key)), nil
+}
+
+// decodeMetaKey decode returned k flag when b flag returned
+func decodeMetaKey(flags MetaFlags) {
+	if !flags.Has('b') {
+		return
+	}
+
+	for i, flag := range flags {
+		if flag.Key != 'k' {
+			continue
+		}
+
+		if key, err := base64.StdEncoding.DecodeString(flag.Token); err == nil {
+			flags[i].Token = string(key)
+		}
+	}
+}
+
+// MetaGet send mg command. key is encoded to base64 by client when b flag defined
+func (c *Client) MetaGet(key string, flags MetaFlags) (*MetaResult, error) {
+	k, err := encodeMetaKey(key, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.meta(fmt.Sprintf("mg %s %s", k, flags), nil, flags)
+}
+
+// MetaSet send ms command with value
+func (c *Client) MetaSet(key string, value []byte, flags MetaFlags) (*MetaResult, error) {
+	k, err := encodeMetaKey(key, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.meta(fmt.Sprintf("ms %s %d %s", k, len(value), flags), value, flags)
+}
+
+// MetaDelete send md command
+func (c *Client) MetaDelete(key string, flags MetaFlags) (*MetaResult, error) {
+	k, err := encodeMetaKey(key, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.meta(fmt.Sprintf("md %s %s", k, flags), nil, flags)
+}
+
+// MetaArithmetic send ma command
+func (c *Client) MetaArithmetic(key string, flags MetaFlags) (*MetaResult, error) {
+	k, err := encodeMetaKey(key, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.meta(fmt.Sprintf("ma %s %s", k, flags), nil, flags)
+}
+
+// MetaNoop send mn command. it is used for end of quiet mode commands
+func (c *Client) MetaNoop() error {
+	err := c.Write("mn")
+	if err != nil {
+		return err
+	}
+
+	res, err := c.readMetaResult()
+	if err != nil {
+		return err
+	}
+
+	if res.Status != MetaNoop {
+		return fmt.Errorf("got unexpected response on mn command: %s", res.Status)
+	}
+
+	return nil
+}
+
+// MetaDebug send me command and return attributes of item
+func (c *Client) MetaDebug(key string, base64Key bool) (*MetaResult, error) {
+	var flags MetaFlags
+
+	if base64Key {
+		flags = MetaFlags{{Key: 'b'}}
+	}
+
+	k, err := encodeMetaKey(key, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.meta(fmt.Sprintf("me %s %s", k, flags), nil, flags)
+}
+
+// meta send meta command and read response.
+// when q flag defined, mn command is sent too for detect end of response
+func (c *Client) meta(cmd string, value []byte, flags MetaFlags) (*MetaResult, error) {
+	var err error
+
+	cmd = strings.TrimSpace(cmd)
+	quiet := flags.Has('q')
+
+	if value != nil {
+		err = c.WriteBlock(cmd, value)
+	} else {
+		err = c.Write(cmd)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if quiet {
+		if err := c.Write("mn"); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := c.readMetaResult()
+	if err != nil {
+		// mn response still follows error of quiet mode command
+		if quiet && !c.broken {
+			c.discardUntilNoop()
+		}
+
+		return nil, err
+	}
+
+	// quiet mode returns response only when command failed (or mg hit)
+	if quiet && res.Status != MetaNoop {
+		noop, err := c.readMetaResult()
+		if err != nil {
+			return nil, err
+		}
+		if noop.Status != MetaNoop {
+			return nil, fmt.Errorf("got unexpected response on mn command: %s", noop.Status)
+		}
+	}
+
+	decodeMetaKey(res.Flags)
+
+	return res, nil
+}
+
+// discardUntilNoop read and discard responses until mn response
+func (c *Client) discardUntilNoop() error {
+	for {
+		buff, err := c.Read()
+		if err != nil {
+			return err
+		}
+
+		if buff == MetaNoop {
+			return nil
+		}
+	}
+}
+
+// readMetaResult read one meta response
+// <CD> <flags>*\r\n
+// VA <size> <flags>*\r\n<data block>\r\n
+// ME <key> <key>=<value>*\r\n
+func (c *Client) readMetaResult() (*MetaResult, error) {
+	buff, err := c.Read()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if isErrorResponse(buff) {
+		return nil, fmt.Errorf("got error from memcached server: %s", buff)
+	}
+
+	f := strings.Fields(buff)
+	if len(f) == 0 {
+		return nil, fmt.Errorf("got empty response from memcached server")
+	}
+
+	res := &MetaResult{Status: f[0]}
+
+	switch res.Status {
+	case MetaValue:
+		if len(f) < 2 {
+			return nil, fmt.Errorf("got malformed value header from memcached server: %s", buff)
+		}
+
+		size, err := strconv.Atoi(f[1])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("got malformed length in value header [%s]", buff)
+		}
+
+		if res.Flags, err = ParseMetaFlags(f[2:]); err != nil {
+			return nil, err
+		}
+
+		if res.Value, err = c.ReadBlock(size); err != nil {
+			return nil, err
+		}
+	case MetaDebug:
+		res.Debug = make(map[string]string)
+
+		for _, attr := range f[2:] {
+			kv := strings.SplitN(attr, "=", 2)
+			if len(kv) == 2 {
+				res.Debug[kv[0]] = kv[1]
+			}
+		}
+	case MetaHeader, MetaEnd, MetaNotStore, MetaExists, MetaNotFound, MetaNoop:
+		if res.Flags, err = ParseMetaFlags(f[1:]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("got unexpected response from memcached server: %s", buff)
+	}
+
+	return res, nil
+}
+
+// printMetaResult display meta command response
+func (c *Client) printMetaResult(key string, res *MetaResult) {
+	desc, ok := metaStatusDescriptions[res.Status]
+	if !ok {
+		desc = "unknown"
+	}
+
+	lines := []string{fmt.Sprintf("%s : %s (%s)", key, res.Status, desc)}
+	fields := []field{{"key", key}, {"status", res.Status}}
+
+	if len(res.Flags) > 0 {
+		lines = append(lines, fmt.Sprintf("  flags : %s", res.Flags.describe()))
+		fields = append(fields, field{"flags", res.Flags.String()})
+	}
+
+	if res.Debug != nil {
+		attrs := make([]string, 0, len(res.Debug))
+		for k := range res.Debug {
+			attrs = append(attrs, k)
+		}
+		sort.Strings(attrs)
+
+		for _, k := range attrs {
+			lines = append(lines, fmt.Sprintf("  %s : %s", k, res.Debug[k]))
+			fields = append(fields, field{k, res.Debug[k]})
+		}
+	}
+
+	r := &record{fields: fields}
+
+	if res.Status == MetaValue {
+		lines = append(lines, fmt.Sprintf("  value : %s", res.Value))
+		r.fields = append(r.fields, field{"value", res.Value})
+		r.value = res.Value
+		r.hasValue = true
+	}
+
+	r.text = strings.Join(lines, "\n")
+	c.out.write(r)
+}
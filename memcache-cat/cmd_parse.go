@@ -2,36 +2,61 @@ package mccat
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func parseCmd(cmd string) (*cmds, error) {
+func parseCmd(line string) (*cmds, error) {
+	args, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
 
+	// empty line
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	return parseArgs(args)
+}
+
+// parseArgs parse command arguments which are already split
+func parseArgs(args []string) (*cmds, error) {
 	c := &cmds{
 		argv:        nil,
 		maxArgCount: 1,
 		getall:      false,
+		store:       false,
 		ops: options{
-			namespace:  "",
-			vnamespace: "",
-			grep:       "",
-			vgrep:      "",
-			keyOnly:    true,
-			countOnly:  false,
+			keyOnly:   true,
+			countOnly: false,
+			flags:     0,
 		},
 	}
 
-	args := strings.Split(cmd, " ")
 	maxArgs := len(args)
 
-	cmd = strings.ToLower(args[0])
+	cmd := strings.ToLower(args[0])
 
 	switch cmd {
-	case "get":
+	case "get", "gets", "gat", "gats":
 		c.maxArgCount = 0
+		c.retrieve = true
+		break
+	case "touch":
+		c.maxArgCount = 3
+		break
+	case "edit":
+		c.maxArgCount = 3
+		break
+	case "mg", "ms", "md", "ma", "mn", "me":
+		c.maxArgCount = 0
+		c.meta = true
 		break
 	case "getall", "get_all":
-		c.maxArgCount = 10
+		c.maxArgCount = 0
 		c.getall = true
 		cmd = "getall"
 		break
@@ -40,11 +65,31 @@ func parseCmd(cmd string) (*cmds, error) {
 		c.ops.countOnly = true
 		cmd = "keycounts"
 		break
+	case "version":
+		c.maxArgCount = 1
+		break
+	case "stats":
+		c.maxArgCount = 0
+		break
+	case "output", "display":
+		c.maxArgCount = 2
+		break
 	case "flushall", "flush_all", "flush":
 		c.maxArgCount = 1
 		cmd = "flushall"
-	case "set", "add", "replace", "append", "prepend":
-		c.maxArgCount = 3
+	case "set", "add", "replace":
+		c.maxArgCount = 0
+		c.store = true
+		c.valueIndex = 3
+		break
+	case "cas":
+		c.maxArgCount = 0
+		c.store = true
+		c.valueIndex = 4
+		break
+	case "append", "prepend":
+		c.maxArgCount = 0
+		c.valueIndex = 3
 		break
 	case "del", "delete", "rm", "remove":
 		c.maxArgCount = 0
@@ -53,8 +98,12 @@ func parseCmd(cmd string) (*cmds, error) {
 		c.maxArgCount = 3
 		break
 	case "help":
-		// show usage
-		usage()
+		// show usage (or manual of command)
+		if maxArgs > 1 {
+			commandUsage(args[1])
+		} else {
+			usage()
+		}
 		return nil, nil
 	default:
 		return nil, fmt.Errorf("wrong command %s", cmd)
@@ -62,50 +111,247 @@ func parseCmd(cmd string) (*cmds, error) {
 
 	c.argv = append(c.argv, cmd)
 
+	// show manual of command
+	if maxArgs == 2 && (args[1] == "--help" || args[1] == "-h") {
+		commandUsage(cmd)
+		return nil, nil
+	}
+
 	if c.maxArgCount > 0 {
 		if maxArgs > c.maxArgCount {
-			usage()
+			commandUsage(cmd)
 			return nil, fmt.Errorf("wrong command %s", cmd)
 		}
 	}
 
+	// meta command flags are passed through as is (h is hit before flag, not help)
+	if c.meta {
+		for i := 1; i < maxArgs; i++ {
+			c.argv = append(c.argv, args[i])
+		}
+
+		return c, nil
+	}
+
 	// parse ope command options (only one option allowed. default is usage)
 	for i := 1; i < maxArgs; i++ {
 		argv := args[i]
 
+		// rest of storage command line is inline value (options must be placed before value,
+		// and value after -- is taken as is even if it looks like option)
+		if c.valueIndex > 0 && len(c.argv) == c.valueIndex && !isValueOption(argv) {
+			if argv == "--" {
+				i++
+			}
+
+			c.value = []byte(strings.Join(args[i:], " "))
+			c.hasValue = true
+			break
+		}
+
 		switch argv {
 		case "--name", "-n":
 			if i+1 < maxArgs && c.getall {
-				c.ops.namespace = args[i+1]
+				c.ops.filter.Namespace = args[i+1]
 			} else {
-				usage()
+				commandUsage(cmd)
 				return nil, fmt.Errorf("failed on parse command")
 			}
 			i++
 			break
 		case "--vname", "-vn":
 			if i+1 < maxArgs && c.getall {
-				c.ops.vnamespace = args[i+1]
+				c.ops.filter.ExcludeNamespace = args[i+1]
 			} else {
-				usage()
+				commandUsage(cmd)
 				return nil, fmt.Errorf("failed on parse command")
 			}
 			i++
 			break
 		case "--grep", "-g":
 			if i+1 < maxArgs && c.getall {
-				c.ops.grep = args[i+1]
+				c.ops.filter.Grep = append(c.ops.filter.Grep, args[i+1])
 			} else {
-				usage()
+				commandUsage(cmd)
 				return nil, fmt.Errorf("failed on parse command")
 			}
 			i++
 			break
 		case "--vgrep", "-vg":
 			if i+1 < maxArgs && c.getall {
-				c.ops.vgrep = args[i+1]
+				c.ops.filter.ExcludeGrep = append(c.ops.filter.ExcludeGrep, args[i+1])
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--or":
+			if c.getall {
+				c.ops.filter.GrepAny = true
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			break
+		case "--regex", "-r":
+			if i+1 < maxArgs && c.getall {
+				re, err := regexp.Compile(args[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("wrong regular expression %s: %s", args[i+1], err.Error())
+				}
+				c.ops.filter.Regex = re
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--glob":
+			if i+1 < maxArgs && c.getall {
+				if _, err := globRegexp(args[i+1]); err != nil {
+					return nil, err
+				}
+				c.ops.filter.Glob = args[i+1]
 			} else {
-				usage()
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--min-size", "--max-size", "--slab":
+			if i+1 < maxArgs && c.getall {
+				n, err := strconv.ParseUint(args[i+1], 10, 31)
+				if err != nil {
+					return nil, fmt.Errorf("%s must be unsigned integer: %s", strings.TrimPrefix(argv, "--"), args[i+1])
+				}
+
+				switch argv {
+				case "--min-size":
+					c.ops.filter.MinSize = int(n)
+				case "--max-size":
+					c.ops.filter.MaxSize = int(n)
+				default:
+					c.ops.filter.Slab = int(n)
+				}
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--expires-within", "--idle-longer-than":
+			if i+1 < maxArgs && c.getall {
+				d, err := parseDuration(args[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("%s must be seconds or duration (ex: 300, 5m, 1h): %s", strings.TrimPrefix(argv, "--"), args[i+1])
+				}
+
+				if argv == "--expires-within" {
+					c.ops.filter.ExpiresWithin = d
+				} else {
+					c.ops.filter.IdleLongerThan = d
+				}
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--no-expiry":
+			if c.getall {
+				c.ops.filter.NoExpiry = true
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			break
+		case "--flags", "-f":
+			if i+1 < maxArgs && c.store {
+				flags, err := strconv.ParseUint(args[i+1], 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("flags must be 32bit unsigned integer: %s", args[i+1])
+				}
+				c.ops.flags = uint32(flags)
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--compress", "-z":
+			if i+1 < maxArgs && c.store {
+				if !isCompressionCodec(args[i+1]) {
+					return nil, fmt.Errorf("unknown compression %s (use %s)", args[i+1], strings.Join(compressionCodecs, ", "))
+				}
+				c.ops.compress = args[i+1]
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--file":
+			if i+1 < maxArgs && c.valueIndex > 0 {
+				c.ops.file = args[i+1]
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--editor", "-e":
+			if c.valueIndex > 0 {
+				c.ops.editor = true
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			break
+		case "--out", "-o", ">":
+			if i+1 < maxArgs && c.retrieve {
+				c.ops.out = args[i+1]
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--decode", "-d":
+			if i+1 < maxArgs && (c.retrieve || c.getall) {
+				if !isDecoderName(args[i+1]) {
+					return nil, fmt.Errorf("unknown decoder %s (use %s)", args[i+1], strings.Join(decoderNames(), ", "))
+				}
+				c.ops.decoder = args[i+1]
+				if c.getall {
+					c.ops.keyOnly = false
+				}
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			i++
+			break
+		case "--hex", "-x", "--escaped":
+			if c.retrieve || c.getall {
+				c.ops.display = displayHex
+				if argv == "--escaped" {
+					c.ops.display = displayEscaped
+				}
+				if c.getall {
+					c.ops.keyOnly = false
+				}
+			} else {
+				commandUsage(cmd)
+				return nil, fmt.Errorf("failed on parse command")
+			}
+			break
+		case "--out-dir":
+			if i+1 < maxArgs && c.getall {
+				c.ops.outDir = args[i+1]
+				c.ops.keyOnly = false
+			} else {
+				commandUsage(cmd)
 				return nil, fmt.Errorf("failed on parse command")
 			}
 			i++
@@ -114,18 +360,64 @@ func parseCmd(cmd string) (*cmds, error) {
 			if c.getall {
 				c.ops.keyOnly = false
 			} else {
-				usage()
+				commandUsage(cmd)
 				return nil, fmt.Errorf("failed on parse command")
 			}
 			break
-		case "help", "h":
-			// show usage
-			usage()
+		case "help", "h", "--help", "-h":
+			// show manual of command
+			commandUsage(cmd)
 			return nil, nil
 		default:
+			// redirect like shell (get key >out.bin)
+			if c.retrieve && len(argv) > 1 && strings.HasPrefix(argv, ">") {
+				c.ops.out = argv[1:]
+				break
+			}
+
 			c.argv = append(c.argv, argv)
 		}
 	}
 
+	if f := c.ops.filter; f.MaxSize > 0 && f.MinSize > f.MaxSize {
+		return nil, fmt.Errorf("min-size %d is larger than max-size %d", f.MinSize, f.MaxSize)
+	}
+
 	return c, nil
 }
+
+// parseDuration parse seconds (ex: 300) or duration (ex: 5m, 1h30m)
+func parseDuration(s string) (time.Duration, error) {
+	if sec, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(sec) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err == nil && d <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %s", s)
+	}
+
+	return d, err
+}
+
+// isValueOption check argument is option of storage command (not start of inline value)
+func isValueOption(arg string) bool {
+	switch arg {
+	case "--flags", "-f", "--compress", "-z", "--editor", "-e", "--file", "--help", "-h":
+		return true
+	}
+
+	return false
+}
+
+// isWriteCommand check command modifies items (include ttl)
+func isWriteCommand(cmd string) bool {
+	switch cmd {
+	case "set", "add", "replace", "append", "prepend", "cas", "touch", "gat", "gats", "edit",
+		"incr", "increase", "decr", "decrease", "del", "delete", "rm", "remove",
+		"ms", "md", "ma", "flushall":
+		return true
+	}
+
+	return false
+}
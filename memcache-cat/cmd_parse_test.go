@@ -0,0 +1,127 @@
+package mccat
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestClient make client which reads response from string and writes commands to buffer.
+// value of commands (ex: ms) is read as "v"
+func newTestClient(response string, config Config) (*Client, *bytes.Buffer) {
+	sent := &bytes.Buffer{}
+
+	c := &Client{
+		config: config,
+		buff:   bufio.NewReadWriter(bufio.NewReader(strings.NewReader(response)), bufio.NewWriter(sent)),
+		out:    &tableFormatter{w: io.Discard},
+		output: outputTable,
+		batch:  true,
+		input:  newLineReader(strings.NewReader("v\n")),
+	}
+	c.proto = &asciiProtocol{c: c}
+
+	return c, sent
+}
+
+func TestParseCmd(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"get a b", []string{"get", "a", "b"}},
+		{`get "a\r\nflush_all"`, []string{"get", "a\r\nflush_all"}},
+		{"set 'a b' 0 v", []string{"set", "a b", "0"}},
+		{"set k 0 --flags 2 v", []string{"set", "k", "0"}},
+		{"get_all --glob 'job:*' -v", []string{"getall"}},
+	}
+
+	for _, tt := range tests {
+		cmds, err := parseCmd(tt.line)
+		if err != nil {
+			t.Errorf("parseCmd(%q): %s", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(cmds.argv, tt.want) {
+			t.Errorf("parseCmd(%q) = %q, want %q", tt.line, cmds.argv, tt.want)
+		}
+	}
+
+	if _, err := parseCmd("get_all --min-size 100 --max-size 10"); err == nil {
+		t.Errorf("min-size larger than max-size is accepted")
+	}
+}
+
+func TestRunRejectsWrongKey(t *testing.T) {
+	long := strings.Repeat("x", maxKeyLength+1)
+
+	lines := []string{
+		`get "a\r\nflush_all"`,
+		`get ok "a\r\nflush_all"`,
+		`gets "a b"`,
+		`gat 10 "a\nb"`,
+		`set "a b" 0 v`,
+		`set "a\r\nflush_all" 0 v`,
+		`add "a\x00" 0 v`,
+		`append "a\x7f" 0 v`,
+		`del "a\r\nflush_all"`,
+		`touch "a b" 10`,
+		`incr "a\nb" 1`,
+		`mg "a b" v`,
+		`ms "a\r\nflush_all" v`,
+		`md "a b"`,
+		`me "a b"`,
+		`stats "items\r\nflush_all"`,
+		"get " + long,
+		"set " + long + " 0 v",
+		`get ""`,
+	}
+
+	for _, line := range lines {
+		cmds, err := parseCmd(line)
+		if err != nil {
+			t.Errorf("parseCmd(%q): %s", line, err)
+			continue
+		}
+
+		c, sent := newTestClient("", Config{ReadOnly: strings.HasPrefix(line, "get")})
+		if err := c.Run(cmds); err == nil {
+			t.Errorf("%q is run without error", line)
+		}
+		if sent.Len() > 0 {
+			t.Errorf("%q sent %q", line, sent.String())
+		}
+	}
+}
+
+func TestRunSendsValidKey(t *testing.T) {
+	tests := []struct {
+		line     string
+		response string
+		want     string
+	}{
+		{`get "a\x41"`, "VALUE aA 0 1\r\nv\r\nEND\r\n", "get aA\r\n"},
+		{`get USER_ERRORS`, "VALUE USER_ERRORS 0 1\r\nv\r\nEND\r\n", "get USER_ERRORS\r\n"},
+		{"set " + strings.Repeat("x", maxKeyLength) + " 0 v", "STORED\r\n", "set " + strings.Repeat("x", maxKeyLength) + " 0 0 1\r\nv\r\n"},
+		{`me "a b" b`, "ME YSBi exp=-1\r\n", "me YSBi b\r\n"},
+	}
+
+	for _, tt := range tests {
+		cmds, err := parseCmd(tt.line)
+		if err != nil {
+			t.Errorf("parseCmd(%q): %s", tt.line, err)
+			continue
+		}
+
+		c, sent := newTestClient(tt.response, Config{})
+		if err := c.Run(cmds); err != nil {
+			t.Errorf("%q: %s", tt.line, err)
+		}
+		if sent.String() != tt.want {
+			t.Errorf("%q sent %q, want %q", tt.line, sent.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package mccat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// saveItem retrieve one key and write its value to file as raw bytes
+func (c *Client) saveItem(cmd string, keys []string, ttl int, path string) error {
+	if len(keys) != 1 {
+		return fmt.Errorf("only one key can be saved to file (use get_all --out-dir for many keys)")
+	}
+
+	item, err := c.proto.retrieve(cmd, keys[0], ttl)
+	if err != nil {
+		return fmt.Errorf("failed to %s key %s: %s", cmd, keys[0], err.Error())
+	}
+
+	return c.writeItemFile(item, path)
+}
+
+// saveItemToDir write value of item to file in dir. file name is encoded key
+func (c *Client) saveItemToDir(item *Item, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory %s: %s", dir, err.Error())
+	}
+
+	return c.writeItemFile(item, filepath.Join(dir, keyFileName(item.Key)))
+}
+
+func (c *Client) writeItemFile(item *Item, path string) error {
+	if err := ioutil.WriteFile(path, item.Value, 0644); err != nil {
+		return fmt.Errorf("cannot save key %s to file: %s", item.Key, err.Error())
+	}
+
+	c.printResult(fmt.Sprintf("key %s saved to %s (%d bytes)", item.Key, path, len(item.Value)),
+		field{"key", item.Key}, field{"file", path}, field{"size", len(item.Value)})
+
+	return nil
+}
+
+// maxFileNameLength is limit of length of file name (255 bytes on most file systems)
+const maxFileNameLength = 255
+
+// keyFileName encode key to safe file name.
+// bytes except alphabets, digits, '.', '-' and '_' are encoded as %XX like URL.
+// long name is shortened to prefix of it and hash of key (~ is not used by encoded key)
+func keyFileName(key string) string {
+	var name strings.Builder
+
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+
+		if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || (ch == '.' && i > 0) {
+			name.WriteByte(ch)
+			continue
+		}
+
+		// leading dot is encoded for avoid hidden file, "." and ".."
+		name.WriteString(fmt.Sprintf("%%%02X", ch))
+	}
+
+	if name.Len() <= maxFileNameLength {
+		return name.String()
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	suffix := "~" + hex.EncodeToString(sum[:16])
+
+	// %XX at the end of prefix is not cut
+	prefix := name.String()[:maxFileNameLength-len(suffix)]
+	if i := strings.LastIndexByte(prefix, '%'); i >= len(prefix)-2 {
+		prefix = prefix[:i]
+	}
+
+	return prefix + suffix
+}
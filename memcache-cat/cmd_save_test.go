@@ -0,0 +1,42 @@
+package mccat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyFileName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"user:1", "user%3A1"},
+		{".hidden", "%2Ehidden"},
+		{"a.b-c_d", "a.b-c_d"},
+		{"a/../b", "a%2F..%2Fb"},
+	}
+
+	for _, tt := range tests {
+		if got := keyFileName(tt.key); got != tt.want {
+			t.Errorf("keyFileName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	// encoded long keys are shortened with hash of key
+	long := strings.Repeat("ns:", maxKeyLength/3)
+	names := map[string]bool{}
+
+	for _, key := range []string{long + "a", long + "b", strings.Repeat("x", maxKeyLength), long[:maxKeyLength-1] + "x"} {
+		name := keyFileName(key)
+		if len(name) > maxFileNameLength {
+			t.Errorf("file name of %q is %d bytes", key, len(name))
+		}
+		if i := strings.LastIndexByte(name, '~'); i >= 0 && strings.LastIndexByte(name[:i], '%') > i-3 {
+			t.Errorf("encoded byte is cut in %s", name)
+		}
+		if names[name] {
+			t.Errorf("file name %s is duplicated", name)
+		}
+		names[name] = true
+	}
+}
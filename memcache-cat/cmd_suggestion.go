@@ -33,29 +33,62 @@ func completerFunc(d prompt.Document) []prompt.Suggest {
 		s = []prompt.Suggest{
 			{Text: "getall --name(-n)", Description: "grep by namespace"},
 			{Text: "getall --vname(-vn)", Description: "grep except namespace"},
-			{Text: "getall --grep(-g)", Description: "grep word in whole key name"},
+			{Text: "getall --grep(-g)", Description: "grep word in whole key name (can be repeated, all words must be matched)"},
+			{Text: "getall --or", Description: "match any of --grep words"},
 			{Text: "getall --vgrep(-vg)", Description: "grep word in whole except key name"},
+			{Text: "getall --regex(-r) [expr]", Description: "regular expression matched with key"},
+			{Text: "getall --glob [pattern]", Description: "shell pattern matched with whole key (*, ? and [...])"},
+			{Text: "getall --min-size [bytes]", Description: "keys which item size is larger than or equal to bytes"},
+			{Text: "getall --max-size [bytes]", Description: "keys which item size is smaller than or equal to bytes"},
+			{Text: "getall --expires-within [duration]", Description: "keys expire within duration (ex: 300, 5m, 1h)"},
+			{Text: "getall --no-expiry", Description: "keys never expire"},
+			{Text: "getall --idle-longer-than [duration]", Description: "keys not accessed longer than duration (ex: 300, 5m, 1h)"},
+			{Text: "getall --slab [id]", Description: "keys in slab class"},
 			{Text: "getall --verbose(-v)", Description: "diaplay result with value like [key : value]"},
+			{Text: "getall --decode(-d) [format]", Description: "decode serialized value (auto, json, php, igbinary, pickle, java, cbor, msgpack)"},
+			{Text: "getall --hex(-x)", Description: "show value as hex dump (--escaped shows Go quoted string)"},
+			{Text: "getall --out-dir", Description: "save value of each key to file in directory"},
 		}
 	} else if strings.HasPrefix(currentLine, "set ") {
 		s = []prompt.Suggest{
 			{Text: "set [key] [ttl]", Description: "type key name and ttl(sec)"},
+			{Text: "set [key] [ttl] --editor(-e)", Description: "input value with $EDITOR"},
+			{Text: "set [key] [ttl] --file [path]", Description: "store content of file (- is stdin)"},
+			{Text: "set [key] [ttl] <<[SENTINEL]", Description: "input multi-line value until SENTINEL line"},
+			{Text: "set [key] [ttl] --flags(-f)", Description: "store with client flags (32bit unsigned integer)"},
+			{Text: "set [key] [ttl] --compress(-z) [codec]", Description: "compress value (gzip, zlib, zstd, snappy, lz4)"},
 		}
 	} else if strings.HasPrefix(currentLine, "add ") {
 		s = []prompt.Suggest{
 			{Text: "add [key] [ttl]", Description: "type key name and ttl(sec)"},
+			{Text: "add [key] [ttl] --editor(-e)", Description: "input value with $EDITOR"},
+			{Text: "add [key] [ttl] --file [path]", Description: "store content of file (- is stdin)"},
+			{Text: "add [key] [ttl] <<[SENTINEL]", Description: "input multi-line value until SENTINEL line"},
+			{Text: "add [key] [ttl] --flags(-f)", Description: "store with client flags (32bit unsigned integer)"},
+			{Text: "add [key] [ttl] --compress(-z) [codec]", Description: "compress value (gzip, zlib, zstd, snappy, lz4)"},
 		}
 	} else if strings.HasPrefix(currentLine, "append ") {
 		s = []prompt.Suggest{
 			{Text: "append [key] [ttl]", Description: "type key name and ttl(sec)"},
+			{Text: "append [key] [ttl] --editor(-e)", Description: "input value with $EDITOR"},
+			{Text: "append [key] [ttl] --file [path]", Description: "store content of file (- is stdin)"},
+			{Text: "append [key] [ttl] <<[SENTINEL]", Description: "input multi-line value until SENTINEL line"},
 		}
 	} else if strings.HasPrefix(currentLine, "prepend ") {
 		s = []prompt.Suggest{
 			{Text: "prepend [key] [ttl]", Description: "type key name and ttl(sec)"},
+			{Text: "prepend [key] [ttl] --editor(-e)", Description: "input value with $EDITOR"},
+			{Text: "prepend [key] [ttl] --file [path]", Description: "store content of file (- is stdin)"},
+			{Text: "prepend [key] [ttl] <<[SENTINEL]", Description: "input multi-line value until SENTINEL line"},
 		}
 	} else if strings.HasPrefix(currentLine, "replace ") {
 		s = []prompt.Suggest{
 			{Text: "replace [key] [ttl]", Description: "type key name and ttl(sec)"},
+			{Text: "replace [key] [ttl] --editor(-e)", Description: "input value with $EDITOR"},
+			{Text: "replace [key] [ttl] --file [path]", Description: "store content of file (- is stdin)"},
+			{Text: "replace [key] [ttl] <<[SENTINEL]", Description: "input multi-line value until SENTINEL line"},
+			{Text: "replace [key] [ttl] --flags(-f)", Description: "store with client flags (32bit unsigned integer)"},
+			{Text: "replace [key] [ttl] --compress(-z) [codec]", Description: "compress value (gzip, zlib, zstd, snappy, lz4)"},
 		}
 	} else if strings.HasPrefix(currentLine, "incr ") {
 		s = []prompt.Suggest{
@@ -81,13 +114,99 @@ func completerFunc(d prompt.Document) []prompt.Suggest {
 		s = []prompt.Suggest{
 			{Text: "remove [key]", Description: "type key name for delete"},
 		}
+	} else if strings.HasPrefix(currentLine, "cas ") {
+		s = []prompt.Suggest{
+			{Text: "cas [key] [ttl] [cas_unique]", Description: "type key name, ttl(sec) and cas unique from gets"},
+			{Text: "cas [key] [ttl] [cas_unique] --flags(-f)", Description: "store with client flags (32bit unsigned integer)"},
+			{Text: "cas [key] [ttl] [cas_unique] --compress(-z) [codec]", Description: "compress value (gzip, zlib, zstd, snappy, lz4)"},
+		}
+	} else if strings.HasPrefix(currentLine, "gat ") {
+		s = []prompt.Suggest{
+			{Text: "gat [ttl] [key]", Description: "type new ttl(sec) and key name for get value"},
+			{Text: "gat [ttl] [key] --decode(-d) [format]", Description: "decode serialized value (auto, json, php, igbinary, pickle, java, cbor, msgpack)"},
+			{Text: "gat [ttl] [key] --hex(-x)", Description: "show value as hex dump (--escaped shows Go quoted string)"},
+		}
+	} else if strings.HasPrefix(currentLine, "gats ") {
+		s = []prompt.Suggest{
+			{Text: "gats [ttl] [key]", Description: "type new ttl(sec) and key name for get value with cas unique"},
+			{Text: "gats [ttl] [key] --decode(-d) [format]", Description: "decode serialized value (auto, json, php, igbinary, pickle, java, cbor, msgpack)"},
+			{Text: "gats [ttl] [key] --hex(-x)", Description: "show value as hex dump (--escaped shows Go quoted string)"},
+		}
+	} else if strings.HasPrefix(currentLine, "edit ") {
+		s = []prompt.Suggest{
+			{Text: "edit [key]", Description: "type key name for edit value (keep remaining ttl)"},
+			{Text: "edit [key] [ttl]", Description: "type key name and new ttl(sec)"},
+		}
+	} else if strings.HasPrefix(currentLine, "touch ") {
+		s = []prompt.Suggest{
+			{Text: "touch [key] [ttl]", Description: "type key name and new ttl(sec)"},
+		}
+	} else if strings.HasPrefix(currentLine, "mg ") {
+		s = []prompt.Suggest{
+			{Text: "mg [key] [flags]", Description: "v:value t:ttl f:flags c:cas s:size h:hit before l:last access k:key b:base64 key O:opaque q:quiet T:update ttl N:vivify R:recache E:set cas"},
+		}
+	} else if strings.HasPrefix(currentLine, "ms ") {
+		s = []prompt.Suggest{
+			{Text: "ms [key] [flags]", Description: "T:ttl F:client flags C:compare cas I:invalidate M:mode(E/A/P/R/S) N:vivify b:base64 key c:return cas k:key O:opaque q:quiet"},
+		}
+	} else if strings.HasPrefix(currentLine, "md ") {
+		s = []prompt.Suggest{
+			{Text: "md [key] [flags]", Description: "C:compare cas I:invalidate T:ttl when invalidate b:base64 key k:key O:opaque q:quiet"},
+		}
+	} else if strings.HasPrefix(currentLine, "ma ") {
+		s = []prompt.Suggest{
+			{Text: "ma [key] [flags]", Description: "M:mode(I/+/D/-) D:delta J:initial value N:vivify T:ttl C:compare cas v:value t:ttl c:cas b:base64 key q:quiet"},
+		}
+	} else if strings.HasPrefix(currentLine, "me ") {
+		s = []prompt.Suggest{
+			{Text: "me [key] [b]", Description: "type key name for show item attributes (b: base64 key)"},
+		}
+	} else if strings.HasPrefix(currentLine, "stats ") {
+		s = []prompt.Suggest{
+			{Text: "stats items", Description: "statistics of items by slab"},
+			{Text: "stats slabs", Description: "statistics of slabs"},
+			{Text: "stats settings", Description: "settings of memcached server"},
+			{Text: "stats conns", Description: "connections of memcached server"},
+		}
+	} else if strings.HasPrefix(currentLine, "output ") {
+		s = []prompt.Suggest{
+			{Text: "output table", Description: "human readable text"},
+			{Text: "output raw", Description: "values as raw bytes"},
+			{Text: "output json", Description: "JSON array of results"},
+			{Text: "output jsonl", Description: "one JSON object per line"},
+			{Text: "output csv", Description: "rows with header line"},
+			{Text: "output tsv", Description: "tab separated rows with header line"},
+		}
+	} else if strings.HasPrefix(currentLine, "display ") {
+		s = []prompt.Suggest{
+			{Text: "display auto", Description: "hex dump only when value is binary"},
+			{Text: "display raw", Description: "values as is"},
+			{Text: "display hex", Description: "values as hex dump"},
+			{Text: "display escaped", Description: "values as Go quoted string"},
+		}
+	} else if strings.HasPrefix(currentLine, "gets ") {
+		s = []prompt.Suggest{
+			{Text: "gets [key]", Description: "type key name for get value with cas unique"},
+			{Text: "gets [key] --decode(-d) [format]", Description: "decode serialized value (auto, json, php, igbinary, pickle, java, cbor, msgpack)"},
+			{Text: "gets [key] --hex(-x)", Description: "show value as hex dump (--escaped shows Go quoted string)"},
+			{Text: "gets [key] --out(-o) [file]", Description: "save value to file as raw bytes (same as > file)"},
+		}
 	} else if strings.HasPrefix(currentLine, "get ") {
 		s = []prompt.Suggest{
 			{Text: "get [key]", Description: "type key name for get value"},
+			{Text: "get [key] --decode(-d) [format]", Description: "decode serialized value (auto, json, php, igbinary, pickle, java, cbor, msgpack)"},
+			{Text: "get [key] --hex(-x)", Description: "show value as hex dump (--escaped shows Go quoted string)"},
+			{Text: "get [key] --out(-o) [file]", Description: "save value to file as raw bytes (same as > file)"},
 		}
 	} else {
 		s = []prompt.Suggest{
 			{Text: "get", Description: "Get data from server"},
+			{Text: "gets", Description: "Get data with cas unique from server"},
+			{Text: "gat", Description: "Get data and update ttl"},
+			{Text: "gats", Description: "Get data with cas unique and update ttl"},
+			{Text: "touch", Description: "Update ttl without rewrite data"},
+			{Text: "cas", Description: "Set data only when not modified since gets"},
+			{Text: "edit", Description: "Edit value with $EDITOR and write back by cas"},
 			{Text: "set", Description: "Set data (overwrite when exist)"},
 			{Text: "add", Description: "Add new data (error when key exist)"},
 			{Text: "append", Description: "Append data from exist data"},
@@ -99,9 +218,19 @@ func completerFunc(d prompt.Document) []prompt.Suggest {
 			{Text: "delete", Description: "Remove key item from server"},
 			{Text: "rm", Description: "Remove key item from server"},
 			{Text: "remove", Description: "Remove key item from server"},
+			{Text: "mg", Description: "Meta get"},
+			{Text: "ms", Description: "Meta set"},
+			{Text: "md", Description: "Meta delete"},
+			{Text: "ma", Description: "Meta arithmetic"},
+			{Text: "mn", Description: "Meta no-op"},
+			{Text: "me", Description: "Meta debug"},
 			{Text: "keycounts", Description: "Get key counts"},
-			{Text: "getall", Description: "Get all items from server (can grep by namespace or key words)"},
+			{Text: "getall", Description: "Get all items from server (can filter keys by name, size, ttl and last access)"},
 			{Text: "flushall", Description: "Delete all keys"},
+			{Text: "stats", Description: "Show statistics of memcached server"},
+			{Text: "version", Description: "Show memcached server version"},
+			{Text: "output", Description: "Show or change output format of command results"},
+			{Text: "display", Description: "Show or change display mode of values"},
 			{Text: "help", Description: "Show usage"},
 			{Text: "exit", Description: "Terminate the mccat"},
 		}
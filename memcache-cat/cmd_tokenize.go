@@ -0,0 +1,110 @@
+package mccat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenize split command line into arguments by spaces like shell.
+// 'single quoted' text is taken as is, and "double quoted" or unquoted text accept
+// backslash escapes (\n, \r, \t, \0, \\, \", \', \<space> and \xNN byte)
+func tokenize(line string) ([]string, error) {
+	var args []string
+	var token strings.Builder
+
+	inToken := false
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			if inToken {
+				args = append(args, token.String())
+				token.Reset()
+				inToken = false
+			}
+		case ch == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in command")
+			}
+
+			token.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		case ch == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] != '\\' {
+					token.WriteByte(line[i])
+					continue
+				}
+
+				n, err := unescape(line, i, &token)
+				if err != nil {
+					return nil, err
+				}
+				i += n
+			}
+
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote in command")
+			}
+			inToken = true
+		case ch == '\\':
+			n, err := unescape(line, i, &token)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			inToken = true
+		default:
+			token.WriteByte(ch)
+			inToken = true
+		}
+	}
+
+	if inToken {
+		args = append(args, token.String())
+	}
+
+	return args, nil
+}
+
+// unescape write escaped byte of backslash at line[i] to token and return length of escape sequence except backslash
+func unescape(line string, i int, token *strings.Builder) (int, error) {
+	if i+1 >= len(line) {
+		return 0, fmt.Errorf("backslash at the end of command")
+	}
+
+	switch line[i+1] {
+	case 'n':
+		token.WriteByte('\n')
+	case 'r':
+		token.WriteByte('\r')
+	case 't':
+		token.WriteByte('\t')
+	case '0':
+		token.WriteByte(0)
+	case 'x':
+		if i+3 >= len(line) {
+			return 0, fmt.Errorf("\\x must be followed by 2 hex digits")
+		}
+
+		b, err := strconv.ParseUint(line[i+2:i+4], 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("\\x must be followed by 2 hex digits: \\x%s", line[i+2:i+4])
+		}
+
+		token.WriteByte(byte(b))
+
+		return 3, nil
+	default:
+		// \\, \", \', \<space> and others are taken as escaped character itself
+		token.WriteByte(line[i+1])
+	}
+
+	return 1, nil
+}
@@ -0,0 +1,51 @@
+package mccat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  get   key  ", []string{"get", "key"}},
+		{"set key 0 'hello world'", []string{"set", "key", "0", "hello world"}},
+		{`set key 0 'a\nb'`, []string{"set", "key", "0", `a\nb`}},
+		{`set key 0 "a\tb \"c\""`, []string{"set", "key", "0", "a\tb \"c\""}},
+		{`set key 0 a\ b\x41\0`, []string{"set", "key", "0", "a bA\x00"}},
+		{`get "a\r\nflush_all"`, []string{"get", "a\r\nflush_all"}},
+		{`get a"b c"d`, []string{"get", "ab cd"}},
+		{`get '' ""`, []string{"get", "", ""}},
+	}
+
+	for _, tt := range tests {
+		got, err := tokenize(tt.line)
+		if err != nil {
+			t.Errorf("tokenize(%q): %s", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeError(t *testing.T) {
+	lines := []string{
+		`set key 0 'hello`,
+		`set key 0 "hello`,
+		`set key 0 "hello\"`,
+		`set key 0 hello\`,
+		`get \x4`,
+		`get \xzz`,
+	}
+
+	for _, line := range lines {
+		if got, err := tokenize(line); err == nil {
+			t.Errorf("tokenize(%q) = %q without error", line, got)
+		}
+	}
+}
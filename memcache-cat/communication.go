@@ -1,24 +1,55 @@
 package mccat
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
+// setDeadline limit time of request which starts from now (when timeout is configured).
+// deadline is not changed while Keys is canceled, for not to override deadline which stops dump
+func (c *Client) setDeadline() {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	if c.config.Timeout > 0 && !c.canceled {
+		c.Conn.SetDeadline(time.Now().Add(c.config.Timeout))
+	}
+}
+
+// commandLine add CRLF to end of command line. CR or LF in the middle of line is error
+// because rest of line is run as another command by server
+func commandLine(cmd string) (string, error) {
+	cmd = strings.TrimRight(cmd, "\r\n")
+	if strings.ContainsAny(cmd, "\r\n") {
+		return "", fmt.Errorf("command must not contain CR or LF: %q", cmd)
+	}
+
+	return cmd + "\r\n", nil
+}
+
 // Write command to memcached server
 func (c *Client) Write(cmd string) error {
 	res := error(nil)
 
 	// set CRLF end of cmd line (memcached recommanded)
-	cmd = strings.TrimRight(cmd, "\r\n") + "\r\n"
+	cmd, err := commandLine(cmd)
+	if err != nil {
+		return err
+	}
+
+	c.setDeadline()
 
-	_, err := c.buff.Writer.WriteString(cmd)
+	_, err = c.buff.Writer.WriteString(cmd)
 	if err != nil {
+		c.broken = true
 		res = fmt.Errorf("failed on sending command to memcached server: %s", err.Error())
 
 	} else {
-		if c.buff.Writer.Flush() != nil {
+		if err := c.buff.Writer.Flush(); err != nil {
+			c.broken = true
 			res = fmt.Errorf("failed on sending command to memcached server: %s", err.Error())
 		}
 	}
@@ -26,12 +57,74 @@ func (c *Client) Write(cmd string) error {
 	return res
 }
 
+// WriteBlock send storage command line and data block to memcached server
+func (c *Client) WriteBlock(cmd string, data []byte) error {
+	cmd, err := commandLine(cmd)
+	if err != nil {
+		return err
+	}
+
+	c.setDeadline()
+
+	if _, err := c.buff.Writer.WriteString(cmd); err != nil {
+		c.broken = true
+		return fmt.Errorf("failed on sending command to memcached server: %s", err.Error())
+	}
+	if _, err := c.buff.Writer.Write(data); err != nil {
+		c.broken = true
+		return fmt.Errorf("failed on sending data block to memcached server: %s", err.Error())
+	}
+	if _, err := c.buff.Writer.WriteString("\r\n"); err != nil {
+		c.broken = true
+		return fmt.Errorf("failed on sending data block to memcached server: %s", err.Error())
+	}
+
+	if err := c.buff.Writer.Flush(); err != nil {
+		c.broken = true
+		return fmt.Errorf("failed on sending command to memcached server: %s", err.Error())
+	}
+
+	return nil
+}
+
 // Read response and trim out CRLF
 func (c *Client) Read() (string, error) {
 	buff, err := c.buff.Reader.ReadString('\n')
 	if err != nil && err != io.EOF {
+		c.broken = true
 		return "", fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
 	}
 
+	// server closed connection
+	if err == io.EOF && len(buff) == 0 {
+		c.broken = true
+		return "", fmt.Errorf("connection closed by memcached server")
+	}
+
 	return strings.TrimRight(buff, "\r\n"), nil
 }
+
+// isErrorResponse check response line is ERROR, CLIENT_ERROR or SERVER_ERROR.
+// prefix is compared because key in response (VALUE or k flag of meta command) may contain "ERROR"
+func isErrorResponse(buff string) bool {
+	return buff == "ERROR" || strings.HasPrefix(buff, "ERROR ") ||
+		strings.HasPrefix(buff, "CLIENT_ERROR") || strings.HasPrefix(buff, "SERVER_ERROR")
+}
+
+// ReadBlock read exact size bytes of data block and its trailing CRLF
+func (c *Client) ReadBlock(size int) ([]byte, error) {
+	buff := make([]byte, size+2)
+
+	if _, err := io.ReadFull(c.buff.Reader, buff); err != nil {
+		c.broken = true
+		return nil, fmt.Errorf("failed on reading data block from memcached server: %s", err.Error())
+	}
+
+	// rest of response cannot be found when length of data block is wrong
+	if !bytes.HasSuffix(buff, []byte("\r\n")) {
+		c.broken = true
+		return nil, fmt.Errorf("data block from memcached server is not terminated by CRLF")
+	}
+
+	return buff[:size], nil
+}
@@ -0,0 +1,314 @@
+package mccat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	compressionGzip   = "gzip"
+	compressionZlib   = "zlib"
+	compressionZstd   = "zstd"
+	compressionSnappy = "snappy"
+	compressionLZ4    = "lz4"
+)
+
+var compressionCodecs = []string{compressionGzip, compressionZlib, compressionZstd, compressionSnappy, compressionLZ4}
+
+// maxDecompressedSize is limit of decompressed value. values are decompressed automatically,
+// so small compressed value which expands to huge data (zip bomb) is shown as is
+const maxDecompressedSize = 64 << 20
+
+var errDecompressedTooLarge = errors.New("decompressed value is larger than 64MB")
+
+// flagRule means value is compressed by codec when all bits of mask are set in flags.
+// lengthPrefix is 4 bytes little endian length of original value before compressed data (php memcached)
+type flagRule struct {
+	mask         uint32
+	codec        string
+	lengthPrefix bool
+}
+
+// compressionConventions are flag bits of compressed value used by memcached client libraries
+var compressionConventions = map[string][]flagRule{
+	// pymemcache and pylibmc (FLAG_COMPRESSED = 1 << 3)
+	"pymemcache": {{mask: 1 << 3, codec: compressionZlib}},
+	// php memcached extension (MEMC_VAL_COMPRESSED = 1 << 4, MEMC_VAL_COMPRESSION_ZLIB = 1 << 5)
+	"php": {{mask: 1<<4 | 1<<5, codec: compressionZlib, lengthPrefix: true}},
+	// spymemcached SerializingTranscoder (COMPRESSED = 1 << 1)
+	"spymemcached": {{mask: 1 << 1, codec: compressionGzip}},
+}
+
+var (
+	snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+	zstdMagic         = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	gzipMagic         = []byte{0x1f, 0x8b}
+	lz4Magic          = []byte{0x04, 0x22, 0x4d, 0x18}
+)
+
+// parseCompressionFlags parse comma separated flag conventions.
+// each of them is name of client library (pymemcache, php, spymemcached) or bit:codec (ex: 16:zstd)
+func parseCompressionFlags(spec string) ([]flagRule, error) {
+	var rules []flagRule
+
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if convention, ok := compressionConventions[strings.ToLower(s)]; ok {
+			rules = append(rules, convention...)
+			continue
+		}
+
+		kv := strings.SplitN(s, ":", 2)
+		if len(kv) != 2 || !isCompressionCodec(kv[1]) {
+			return nil, fmt.Errorf("wrong compression flags %s (use %s or bit:codec)", s, strings.Join(compressionConventionNames(), ", "))
+		}
+
+		mask, err := strconv.ParseUint(kv[0], 0, 32)
+		if err != nil || mask == 0 {
+			return nil, fmt.Errorf("compression flag bit must be 32bit unsigned integer: %s", kv[0])
+		}
+
+		rules = append(rules, flagRule{mask: uint32(mask), codec: kv[1]})
+	}
+
+	return rules, nil
+}
+
+func compressionConventionNames() []string {
+	var names []string
+	for name := range compressionConventions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func isCompressionCodec(name string) bool {
+	for _, codec := range compressionCodecs {
+		if name == codec {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decompress detect compression of value by flags and magic bytes and return decompressed value
+// with codec name. codec is empty when value is not compressed (or cannot be decompressed)
+func (c *Client) decompress(value []byte, flags uint32) ([]byte, string) {
+	for _, rule := range c.compressionRules {
+		if flags&rule.mask != rule.mask {
+			continue
+		}
+
+		data := value
+		if rule.lengthPrefix {
+			if len(data) < 4 {
+				continue
+			}
+			data = data[4:]
+		}
+
+		if decoded, err := decode(rule.codec, data); err == nil {
+			return decoded, rule.codec
+		}
+	}
+
+	codec := detectCompression(value)
+	if codec == "" {
+		return value, ""
+	}
+
+	decoded, err := decode(codec, value)
+	if err != nil {
+		// length prefixed zlib of php memcached
+		if codec == compressionZlib && len(value) > 4 {
+			if decoded, err = decode(codec, value[4:]); err == nil &&
+				int(binary.LittleEndian.Uint32(value)) == len(decoded) {
+				return decoded, codec
+			}
+		}
+
+		return value, ""
+	}
+
+	return decoded, codec
+}
+
+// detectCompression guess codec from magic bytes of value.
+// raw snappy and lz4 blocks have no magic bytes, so they are detected by flags only
+func detectCompression(value []byte) string {
+	switch {
+	case bytes.HasPrefix(value, gzipMagic):
+		return compressionGzip
+	case bytes.HasPrefix(value, zstdMagic):
+		return compressionZstd
+	case bytes.HasPrefix(value, lz4Magic):
+		return compressionLZ4
+	case bytes.HasPrefix(value, snappyStreamMagic):
+		return compressionSnappy
+	case isZlibHeader(value) || (len(value) > 4 && isZlibHeader(value[4:])):
+		return compressionZlib
+	}
+
+	return ""
+}
+
+// isZlibHeader check CMF and FLG of zlib (deflate with window size up to 32K and valid check bits)
+func isZlibHeader(value []byte) bool {
+	if len(value) < 2 {
+		return false
+	}
+
+	return value[0]&0x0f == 8 && value[0]>>4 <= 7 && (uint16(value[0])<<8|uint16(value[1]))%31 == 0
+}
+
+// decode decompress value by codec. decompressed value is limited to maxDecompressedSize
+func decode(codec string, value []byte) ([]byte, error) {
+	switch codec {
+	case compressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(value))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return readLimited(r)
+	case compressionZlib:
+		r, err := zlib.NewReader(bytes.NewReader(value))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return readLimited(r)
+	case compressionZstd:
+		d, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+
+		decoded, err := d.DecodeAll(value, nil)
+		if err == zstd.ErrDecoderSizeExceeded {
+			return nil, errDecompressedTooLarge
+		}
+
+		return decoded, err
+	case compressionSnappy:
+		if bytes.HasPrefix(value, snappyStreamMagic) {
+			return readLimited(snappy.NewReader(bytes.NewReader(value)))
+		}
+
+		// length of raw snappy block is written in its header
+		n, err := snappy.DecodedLen(value)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxDecompressedSize {
+			return nil, errDecompressedTooLarge
+		}
+
+		return snappy.Decode(nil, value)
+	case compressionLZ4:
+		// lz4 output is limited by ratio to input, and checked by same limit as others
+		decoded, err := lz4Decode(value)
+		if err == nil && len(decoded) > maxDecompressedSize {
+			return nil, errDecompressedTooLarge
+		}
+
+		return decoded, err
+	}
+
+	return nil, fmt.Errorf("unknown compression %s", codec)
+}
+
+// readLimited read decompressed stream up to maxDecompressedSize
+func readLimited(r io.Reader) ([]byte, error) {
+	decoded, err := ioutil.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > maxDecompressedSize {
+		return nil, errDecompressedTooLarge
+	}
+
+	return decoded, nil
+}
+
+// compress compress value by codec and return it with flags of configured convention.
+// snappy and lz4 are stored as stream format with magic bytes when no flag is configured for codec
+func (c *Client) compress(codec string, value []byte, flags uint32) ([]byte, uint32, error) {
+	var rule *flagRule
+
+	for i := range c.compressionRules {
+		if c.compressionRules[i].codec == codec {
+			rule = &c.compressionRules[i]
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+
+	switch codec {
+	case compressionGzip:
+		w := gzip.NewWriter(&buf)
+		w.Write(value)
+		w.Close()
+	case compressionZlib:
+		w := zlib.NewWriter(&buf)
+		w.Write(value)
+		w.Close()
+	case compressionZstd:
+		e, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		buf.Write(e.EncodeAll(value, nil))
+		e.Close()
+	case compressionSnappy:
+		if rule != nil {
+			buf.Write(snappy.Encode(nil, value))
+		} else {
+			w := snappy.NewBufferedWriter(&buf)
+			w.Write(value)
+			w.Close()
+		}
+	case compressionLZ4:
+		if rule != nil {
+			buf.Write(lz4EncodeBlock(value))
+		} else {
+			buf.Write(lz4Encode(value))
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown compression %s (use %s)", codec, strings.Join(compressionCodecs, ", "))
+	}
+
+	if rule == nil {
+		return buf.Bytes(), flags, nil
+	}
+
+	compressed := buf.Bytes()
+	if rule.lengthPrefix {
+		compressed = append(binary.LittleEndian.AppendUint32(nil, uint32(len(value))), compressed...)
+	}
+
+	return compressed, flags | rule.mask, nil
+}
@@ -0,0 +1,94 @@
+package mccat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zeroReader is endless zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// compressZeros compress n zero bytes by writer made by newWriter
+func compressZeros(t *testing.T, n int64, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := newWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(w, io.LimitReader(zeroReader{}, n)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	c := &Client{}
+	value := bytes.Repeat([]byte("memcached "), 100)
+
+	for _, codec := range compressionCodecs {
+		compressed, _, err := c.compress(codec, value, 0)
+		if err != nil {
+			t.Errorf("%s: %s", codec, err)
+			continue
+		}
+
+		decoded, got := c.decompress(compressed, 0)
+		if got != codec || !bytes.Equal(decoded, value) {
+			t.Errorf("%s: decompressed as %q (%d bytes)", codec, got, len(decoded))
+		}
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	const size = maxDecompressedSize + 1<<20
+
+	// raw snappy block has only length in header
+	snappyHeader := binary.AppendUvarint(nil, size)
+
+	tests := []struct {
+		codec string
+		value []byte
+	}{
+		{compressionGzip, compressZeros(t, size, func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil })},
+		{compressionZlib, compressZeros(t, size, func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil })},
+		{compressionZstd, compressZeros(t, size, func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) })},
+		{compressionSnappy, compressZeros(t, size, func(w io.Writer) (io.WriteCloser, error) { return snappy.NewBufferedWriter(w), nil })},
+		{compressionSnappy, append(snappyHeader, 0)},
+		{compressionLZ4, lz4Encode(make([]byte, size))},
+	}
+
+	c := &Client{compressionRules: []flagRule{{mask: 1, codec: compressionSnappy}}}
+
+	for _, tt := range tests {
+		if out, err := decode(tt.codec, tt.value); err != errDecompressedTooLarge {
+			t.Errorf("%s: %d bytes is decoded to %d bytes: %v", tt.codec, len(tt.value), len(out), err)
+		}
+
+		// value is shown as is
+		if out, codec := c.decompress(tt.value, 1); codec != "" || !bytes.Equal(out, tt.value) {
+			t.Errorf("%s: %d bytes is decompressed to %d bytes as %q", tt.codec, len(tt.value), len(out), codec)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	decodeAuto = "auto"
+	decodeNone = "none"
+	// maxDecodeDepth is limit of nested containers for broken or malicious values
+	maxDecodeDepth = 512
+	// maxDecodedSize is limit of rendered JSON. indent of deep nested values and shared values
+	// can make it much larger than value
+	maxDecodedSize = 16 << 20
+)
+
+var errDecodedTooLarge = fmt.Errorf("decoded value is larger than %dMB", maxDecodedSize>>20)
+
+// decoder render serialized value as readable structure
+type decoder struct {
+	name string
+	// detect check value looks like this format (for auto detection)
+	detect func(value []byte) bool
+	// decode parse value into nil, bool, int64, uint64, *big.Int, float64, string, []byte,
+	// []interface{} or *orderedMap
+	decode func(value []byte) (interface{}, error)
+}
+
+// decoders are tried in this order by auto detection
+var decoders = []*decoder{
+	{name: "json", detect: isJSONValue, decode: decodeJSON},
+	{name: "php", detect: isPHPSerialized, decode: decodePHP},
+	{name: "igbinary", detect: isIgbinary, decode: decodeIgbinary},
+	{name: "pickle", detect: isPickle, decode: decodePickle},
+	{name: "java", detect: isJavaSerialized, decode: decodeJava},
+	{name: "cbor", detect: isCBOR, decode: decodeCBOR},
+	{name: "msgpack", detect: isMsgpack, decode: decodeMsgpack},
+}
+
+func findDecoder(name string) *decoder {
+	for _, d := range decoders {
+		if d.name == name {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// decoderNames return names which can be used by --decode
+func decoderNames() []string {
+	names := []string{decodeAuto, decodeNone}
+	for _, d := range decoders {
+		names = append(names, d.name)
+	}
+
+	return names
+}
+
+func isDecoderName(name string) bool {
+	for _, n := range decoderNames() {
+		if name == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeValue decode value by decoder name (auto detect format by auto) and return
+// readable JSON with name of format. format is empty when value is not decoded
+func decodeValue(value []byte, name string) ([]byte, string, error) {
+	if name == "" || name == decodeNone {
+		return value, "", nil
+	}
+
+	if name != decodeAuto {
+		d := findDecoder(name)
+		if d == nil {
+			return value, "", fmt.Errorf("unknown decoder %s (use %s)", name, strings.Join(decoderNames(), ", "))
+		}
+
+		v, err := d.decode(value)
+		if err == nil {
+			var rendered []byte
+			if rendered, err = renderDecoded(v); err == nil {
+				return rendered, d.name, nil
+			}
+		}
+
+		return value, "", fmt.Errorf("cannot decode value as %s: %s", name, err.Error())
+	}
+
+	for _, d := range decoders {
+		if !d.detect(value) {
+			continue
+		}
+
+		if v, err := d.decode(value); err == nil {
+			rendered, err := renderDecoded(v)
+			if err != nil {
+				return value, "", fmt.Errorf("cannot decode value as %s: %s", d.name, err.Error())
+			}
+
+			return rendered, d.name, nil
+		}
+	}
+
+	return value, "", nil
+}
+
+// orderedMap is map which keeps order of keys (keys are not only string in some formats)
+type orderedMap struct {
+	keys   []interface{}
+	values []interface{}
+}
+
+func (m *orderedMap) set(key, value interface{}) {
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// renderDecoded encode decoded value to indented JSON (up to maxDecodedSize)
+func renderDecoded(v interface{}) ([]byte, error) {
+	w := &decodedWriter{indent: true}
+	if !w.value(v, 0) {
+		return nil, errDecodedTooLarge
+	}
+
+	return w.buf.Bytes(), nil
+}
+
+// decodedWriter write decoded value as JSON (indented like json.Indent when indent is true)
+type decodedWriter struct {
+	buf    bytes.Buffer
+	indent bool
+}
+
+// newline start line of element in container
+func (w *decodedWriter) newline(depth int) {
+	if !w.indent {
+		return
+	}
+
+	w.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		w.buf.WriteString("  ")
+	}
+}
+
+// value write v and return false when output is over maxDecodedSize
+func (w *decodedWriter) value(v interface{}, depth int) bool {
+	if w.buf.Len() > maxDecodedSize {
+		return false
+	}
+
+	buf := &w.buf
+
+	switch t := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case int64:
+		buf.WriteString(strconv.FormatInt(t, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(t, 10))
+	case *big.Int:
+		buf.WriteString(t.String())
+	case json.Number:
+		buf.WriteString(t.String())
+	case float64:
+		// NaN and Inf are not allowed in JSON
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			writeJSONString(buf, strconv.FormatFloat(t, 'g', -1, 64))
+		} else {
+			buf.WriteString(strconv.FormatFloat(t, 'g', -1, 64))
+		}
+	case string:
+		writeJSONString(buf, t)
+	case []byte:
+		writeJSONString(buf, bytesText(t))
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			w.newline(depth + 1)
+			if !w.value(e, depth+1) {
+				return false
+			}
+		}
+		if len(t) > 0 {
+			w.newline(depth)
+		}
+		buf.WriteByte(']')
+	case *orderedMap:
+		buf.WriteByte('{')
+		for i, k := range t.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			w.newline(depth + 1)
+			writeJSONString(buf, keyText(k))
+			buf.WriteByte(':')
+			if w.indent {
+				buf.WriteByte(' ')
+			}
+			if !w.value(t.values[i], depth+1) {
+				return false
+			}
+		}
+		if len(t.keys) > 0 {
+			w.newline(depth)
+		}
+		buf.WriteByte('}')
+	default:
+		writeJSONString(buf, fmt.Sprint(t))
+	}
+
+	return w.buf.Len() <= maxDecodedSize
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+
+	// Encode append line break
+	buf.Truncate(buf.Len() - 1)
+}
+
+// bytesText return bytes as text, or hex with 0x prefix when it is not valid UTF-8
+func bytesText(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+
+	return "0x" + hex.EncodeToString(b)
+}
+
+// keyText return map key as string of JSON object
+func keyText(k interface{}) string {
+	switch t := k.(type) {
+	case string:
+		return t
+	case []byte:
+		return bytesText(t)
+	case nil, bool, int64, uint64, float64, *big.Int, json.Number:
+		return fmt.Sprint(t)
+	}
+
+	w := &decodedWriter{}
+	w.value(k, 0)
+
+	return w.buf.String()
+}
+
+// isList check keys of PHP array are 0, 1, 2 ... (rendered as JSON array)
+func isList(m *orderedMap) bool {
+	for i, k := range m.keys {
+		if n, ok := k.(int64); !ok || n != int64(i) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isJSONValue(value []byte) bool {
+	v := bytes.TrimSpace(value)
+
+	return len(v) > 0 && (v[0] == '{' || v[0] == '[') && json.Valid(v)
+}
+
+// decodeJSON parse JSON keeping order of object keys
+func decodeJSON(value []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+
+	v, err := decodeJSONToken(dec, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if dec.More() {
+		return nil, fmt.Errorf("extra data after JSON value")
+	}
+
+	return v, nil
+}
+
+func decodeJSONToken(dec *json.Decoder, depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("too deep nested value")
+	}
+
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '[':
+		list := []interface{}{}
+		for dec.More() {
+			v, err := decodeJSONToken(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err = dec.Token()
+
+		return list, err
+	case '{':
+		m := &orderedMap{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := decodeJSONToken(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m.set(key, v)
+		}
+		_, err = dec.Token()
+
+		return m, err
+	}
+
+	return nil, fmt.Errorf("unexpected %s in JSON", delim)
+}
+
+// byteReader read bytes of binary format with bounds check
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteReader) remain() int {
+	return len(r.data) - r.pos
+}
+
+func (r *byteReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errUnexpectedEnd
+	}
+
+	r.pos++
+
+	return r.data[r.pos-1], nil
+}
+
+func (r *byteReader) peek() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errUnexpectedEnd
+	}
+
+	return r.data[r.pos], nil
+}
+
+func (r *byteReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remain() {
+		return nil, errUnexpectedEnd
+	}
+
+	r.pos += n
+
+	return r.data[r.pos-n : r.pos], nil
+}
+
+// uint read n bytes big endian unsigned integer
+func (r *byteReader) uint(n int) (uint64, error) {
+	b, err := r.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+
+	return v, nil
+}
+
+// count read n bytes length of container, and check it is not larger than rest of data
+func (r *byteReader) count(n int) (int, error) {
+	v, err := r.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(r.remain()) {
+		return 0, fmt.Errorf("length %d is larger than data", v)
+	}
+
+	return int(v), nil
+}
+
+var errUnexpectedEnd = fmt.Errorf("unexpected end of data")
@@ -0,0 +1,308 @@
+package mccat
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+// CBOR format (RFC 8949)
+
+const (
+	cborUnsigned = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborIndefinite = 31
+	cborBreak      = 0xff
+	cborSelfDesc   = 55799
+)
+
+var cborSelfDescMagic = []byte{0xd9, 0xd9, 0xf7}
+
+// isCBOR check value starts with self-described CBOR tag or map.
+// array is detected as msgpack because their first bytes are overlapped
+func isCBOR(value []byte) bool {
+	if bytes.HasPrefix(value, cborSelfDescMagic) {
+		return true
+	}
+
+	return len(value) > 0 && (0xa0 <= value[0] && value[0] <= 0xbf)
+}
+
+func decodeCBOR(value []byte) (interface{}, error) {
+	r := &byteReader{data: value}
+
+	v, err := cborValue(r, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s at offset %d", err.Error(), r.pos)
+	}
+
+	if v == cborBreakMark {
+		return nil, fmt.Errorf("unexpected break")
+	}
+
+	if r.remain() > 0 {
+		return nil, fmt.Errorf("extra data at offset %d", r.pos)
+	}
+
+	return v, nil
+}
+
+// cborBreakMark is returned when break of indefinite length item is read
+var cborBreakMark = &struct{}{}
+
+// cborArgument read argument of initial byte. indefinite is true for additional information 31
+func cborArgument(r *byteReader, info byte) (uint64, bool, error) {
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info <= 27:
+		v, err := r.uint(1 << (info - 24))
+		return v, false, err
+	case info == cborIndefinite:
+		return 0, true, nil
+	}
+
+	return 0, false, fmt.Errorf("wrong additional information %d", info)
+}
+
+func cborValue(r *byteReader, depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("too deep nested value")
+	}
+
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	if b == cborBreak {
+		return cborBreakMark, nil
+	}
+
+	major, info := b>>5, b&0x1f
+
+	// floats are encoded by bits in argument
+	if major == cborSimple {
+		return cborSimpleValue(r, info)
+	}
+
+	arg, indefinite, err := cborArgument(r, info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		return arg, nil
+	case cborNegative:
+		if arg < math.MaxInt64 {
+			return -1 - int64(arg), nil
+		}
+
+		n := new(big.Int).SetUint64(arg)
+
+		return n.Neg(n).Sub(n, big.NewInt(1)), nil
+	case cborBytes, cborText:
+		data, err := cborString(r, major, arg, indefinite, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		if major == cborText {
+			return string(data), nil
+		}
+
+		return data, nil
+	case cborArray:
+		list := []interface{}{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			v, err := cborValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if v == cborBreakMark {
+				if !indefinite {
+					return nil, fmt.Errorf("unexpected break")
+				}
+				break
+			}
+
+			list = append(list, v)
+		}
+
+		return list, nil
+	case cborMap:
+		m := &orderedMap{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			k, err := cborValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if k == cborBreakMark {
+				if !indefinite {
+					return nil, fmt.Errorf("unexpected break")
+				}
+				break
+			}
+
+			v, err := cborValue(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if v == cborBreakMark {
+				return nil, fmt.Errorf("unexpected break")
+			}
+
+			m.set(k, v)
+		}
+
+		return m, nil
+	case cborTag:
+		v, err := cborValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if v == cborBreakMark {
+			return nil, fmt.Errorf("unexpected break")
+		}
+
+		return cborTagged(arg, v), nil
+	}
+
+	return nil, fmt.Errorf("unknown major type %d", major)
+}
+
+// cborString read bytes of definite length string, or concatenate chunks of indefinite length string
+func cborString(r *byteReader, major byte, n uint64, indefinite bool, depth int) ([]byte, error) {
+	if !indefinite {
+		if n > uint64(r.remain()) {
+			return nil, errUnexpectedEnd
+		}
+
+		return r.bytes(int(n))
+	}
+
+	var data []byte
+	for {
+		chunk, err := cborValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if chunk == cborBreakMark {
+			return data, nil
+		}
+
+		switch c := chunk.(type) {
+		case []byte:
+			data = append(data, c...)
+		case string:
+			data = append(data, c...)
+		default:
+			return nil, fmt.Errorf("wrong chunk of indefinite length string")
+		}
+	}
+}
+
+func cborSimpleValue(r *byteReader, info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// null and undefined
+		return nil, nil
+	case 24:
+		v, err := r.byte()
+		return fmt.Sprintf("simple(%d)", v), err
+	case 25:
+		v, err := r.uint(2)
+		return halfFloat(uint16(v)), err
+	case 26:
+		v, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 27:
+		v, err := r.uint(8)
+		return math.Float64frombits(v), err
+	}
+
+	if info < 20 {
+		return fmt.Sprintf("simple(%d)", info), nil
+	}
+
+	return nil, fmt.Errorf("wrong simple value %d", info)
+}
+
+// halfFloat convert IEEE 754 half precision float to float64
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -v
+	}
+
+	return v
+}
+
+// cborTagged convert well known tags (date time, bignum) and show others with tag number
+func cborTagged(tag uint64, v interface{}) interface{} {
+	switch tag {
+	case cborSelfDesc:
+		return v
+	case 0:
+		if s, ok := v.(string); ok {
+			return s
+		}
+	case 1:
+		switch t := v.(type) {
+		case uint64:
+			return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+		case int64:
+			return time.Unix(t, 0).UTC().Format(time.RFC3339)
+		case float64:
+			sec, frac := math.Modf(t)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+		}
+	case 2, 3:
+		if b, ok := v.([]byte); ok {
+			n := new(big.Int).SetBytes(b)
+			if tag == 3 {
+				n.Neg(n).Sub(n, big.NewInt(1))
+			}
+
+			return n
+		}
+	}
+
+	tagged := &orderedMap{}
+	tagged.set("@tag", tag)
+	tagged.set("value", v)
+
+	return tagged
+}
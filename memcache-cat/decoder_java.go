@@ -0,0 +1,512 @@
+package mccat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Java object serialization stream (https://docs.oracle.com/javase/8/docs/platform/serialization/spec/protocol.html).
+// class descriptors and field values are shown. data written by writeObject of class
+// is shown as @annotations, and fields of unknown data are stopped with @error
+
+const (
+	javaMagic   = 0xaced
+	javaVersion = 5
+
+	javaNull           = 0x70
+	javaReference      = 0x71
+	javaClassDesc      = 0x72
+	javaObject         = 0x73
+	javaString         = 0x74
+	javaArray          = 0x75
+	javaClass          = 0x76
+	javaBlockData      = 0x77
+	javaEndBlockData   = 0x78
+	javaReset          = 0x79
+	javaBlockDataLong  = 0x7a
+	javaException      = 0x7b
+	javaLongString     = 0x7c
+	javaProxyClassDesc = 0x7d
+	javaEnum           = 0x7e
+
+	javaBaseHandle = 0x7e0000
+
+	javaSCWriteMethod    = 0x01
+	javaSCSerializable   = 0x02
+	javaSCExternalizable = 0x04
+	javaSCBlockData      = 0x08
+)
+
+type javaField struct {
+	typeCode  byte
+	name      string
+	className string
+}
+
+type javaClassDescriptor struct {
+	name   string
+	suid   int64
+	flags  byte
+	fields []javaField
+	super  *javaClassDescriptor
+}
+
+func (d *javaClassDescriptor) String() string {
+	if d == nil {
+		return "null"
+	}
+
+	return "class " + d.name
+}
+
+type javaReader struct {
+	byteReader
+	handles []interface{}
+	depth   int
+}
+
+func isJavaSerialized(value []byte) bool {
+	return len(value) > 4 && binary.BigEndian.Uint16(value) == javaMagic && binary.BigEndian.Uint16(value[2:]) == javaVersion
+}
+
+func decodeJava(value []byte) (interface{}, error) {
+	if !isJavaSerialized(value) {
+		return nil, fmt.Errorf("unknown java serialization header")
+	}
+
+	r := &javaReader{byteReader: byteReader{data: value, pos: 4}}
+
+	var contents []interface{}
+	for r.remain() > 0 {
+		v, err := r.content()
+		if err != nil {
+			// show parsed data with error (custom data of class cannot be read without class)
+			if v != nil {
+				contents = append(contents, v)
+			}
+			contents = append(contents, fmt.Sprintf("@error: %s at offset %d", err.Error(), r.pos))
+			break
+		}
+
+		contents = append(contents, v)
+	}
+
+	if len(contents) == 1 {
+		return contents[0], nil
+	}
+
+	return contents, nil
+}
+
+func (r *javaReader) newHandle(v interface{}) int {
+	r.handles = append(r.handles, v)
+
+	return len(r.handles) - 1
+}
+
+// content read object or block data
+func (r *javaReader) content() (interface{}, error) {
+	t, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case javaBlockData, javaBlockDataLong:
+		r.pos++
+
+		size := 1
+		if t == javaBlockDataLong {
+			size = 4
+		}
+
+		n, err := r.count(size)
+		if err != nil {
+			return nil, err
+		}
+
+		return r.bytes(n)
+	}
+
+	return r.object()
+}
+
+func (r *javaReader) object() (interface{}, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+
+	if r.depth > maxDecodeDepth {
+		return nil, fmt.Errorf("too deep nested value")
+	}
+
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case javaNull:
+		return nil, nil
+	case javaReference:
+		h, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		i := int(h) - javaBaseHandle
+		if i < 0 || i >= len(r.handles) {
+			return nil, fmt.Errorf("wrong handle 0x%x", h)
+		}
+
+		// referred object is shown by reference for avoid recursion
+		switch v := r.handles[i].(type) {
+		case string:
+			return v, nil
+		case *javaClassDescriptor:
+			return v, nil
+		}
+
+		return fmt.Sprintf("@ref 0x%x", h), nil
+	case javaString, javaLongString:
+		size := 2
+		if t == javaLongString {
+			size = 8
+		}
+
+		n, err := r.count(size)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := r.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+
+		s := string(b)
+		r.newHandle(s)
+
+		return s, nil
+	case javaClassDesc, javaProxyClassDesc:
+		r.pos--
+		return r.classDesc()
+	case javaClass:
+		desc, err := r.classDesc()
+		if err != nil {
+			return nil, err
+		}
+		if desc == nil {
+			return nil, fmt.Errorf("class descriptor of class is null")
+		}
+		r.newHandle(desc)
+
+		return "class " + desc.name, nil
+	case javaEnum:
+		desc, err := r.classDesc()
+		if err != nil {
+			return nil, err
+		}
+		if desc == nil {
+			return nil, fmt.Errorf("class descriptor of enum is null")
+		}
+		h := r.newHandle(nil)
+
+		name, err := r.object()
+		if err != nil {
+			return nil, err
+		}
+
+		v := fmt.Sprintf("%s.%v", desc.name, name)
+		r.handles[h] = v
+
+		return v, nil
+	case javaArray:
+		return r.array()
+	case javaObject:
+		return r.newObject()
+	case javaReset:
+		r.handles = nil
+		return r.object()
+	case javaException:
+		return nil, fmt.Errorf("exception is written in stream")
+	}
+
+	return nil, fmt.Errorf("unknown type code 0x%02x", t)
+}
+
+// classDesc read class descriptor (new descriptor, reference or null)
+func (r *javaReader) classDesc() (*javaClassDescriptor, error) {
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case javaNull:
+		return nil, nil
+	case javaReference:
+		r.pos--
+
+		v, err := r.object()
+		if err != nil {
+			return nil, err
+		}
+
+		desc, ok := v.(*javaClassDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("reference is not class descriptor")
+		}
+
+		return desc, nil
+	case javaProxyClassDesc:
+		desc := &javaClassDescriptor{name: "proxy"}
+		r.newHandle(desc)
+
+		n, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := uint64(0); i < n; i++ {
+			name, err := r.utf()
+			if err != nil {
+				return nil, err
+			}
+			desc.name += " " + name
+		}
+
+		if err := r.skipAnnotation(); err != nil {
+			return nil, err
+		}
+
+		desc.super, err = r.classDesc()
+
+		return desc, err
+	case javaClassDesc:
+	default:
+		return nil, fmt.Errorf("unknown class descriptor 0x%02x", t)
+	}
+
+	name, err := r.utf()
+	if err != nil {
+		return nil, err
+	}
+
+	suid, err := r.uint(8)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &javaClassDescriptor{name: name, suid: int64(suid)}
+	r.newHandle(desc)
+
+	if desc.flags, err = r.byte(); err != nil {
+		return nil, err
+	}
+
+	n, err := r.uint(2)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < n; i++ {
+		var f javaField
+
+		if f.typeCode, err = r.byte(); err != nil {
+			return nil, err
+		}
+		if f.name, err = r.utf(); err != nil {
+			return nil, err
+		}
+
+		if f.typeCode == '[' || f.typeCode == 'L' {
+			v, err := r.object()
+			if err != nil {
+				return nil, err
+			}
+			f.className = fmt.Sprint(v)
+		}
+
+		desc.fields = append(desc.fields, f)
+	}
+
+	if err := r.skipAnnotation(); err != nil {
+		return nil, err
+	}
+
+	desc.super, err = r.classDesc()
+
+	return desc, err
+}
+
+// skipAnnotation skip contents until end of block data
+func (r *javaReader) skipAnnotation() error {
+	_, err := r.annotation()
+
+	return err
+}
+
+// annotation read contents until end of block data
+func (r *javaReader) annotation() ([]interface{}, error) {
+	var contents []interface{}
+
+	for {
+		t, err := r.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if t == javaEndBlockData {
+			r.pos++
+			return contents, nil
+		}
+
+		v, err := r.content()
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, v)
+	}
+}
+
+func (r *javaReader) utf() (string, error) {
+	n, err := r.count(2)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := r.bytes(n)
+
+	return string(b), err
+}
+
+func (r *javaReader) newObject() (interface{}, error) {
+	desc, err := r.classDesc()
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return nil, fmt.Errorf("class descriptor of object is null")
+	}
+
+	obj := &orderedMap{}
+	obj.set("@class", desc.name)
+	obj.set("@serialVersionUID", desc.suid)
+	r.newHandle(obj)
+
+	// class data is written from super class
+	var chain []*javaClassDescriptor
+	for d := desc; d != nil; d = d.super {
+		if len(chain) > maxDecodeDepth {
+			return nil, fmt.Errorf("too deep class hierarchy")
+		}
+		chain = append([]*javaClassDescriptor{d}, chain...)
+	}
+
+	// parsed fields are returned with error
+
+	for _, d := range chain {
+		if d.flags&javaSCExternalizable != 0 {
+			if d.flags&javaSCBlockData == 0 {
+				return obj, fmt.Errorf("externalizable class %s without block data", d.name)
+			}
+
+			contents, err := r.annotation()
+			if err != nil {
+				return obj, err
+			}
+			obj.set("@annotations", contents)
+
+			continue
+		}
+
+		for _, f := range d.fields {
+			v, err := r.fieldValue(f.typeCode)
+			if err != nil {
+				return obj, fmt.Errorf("%s of field %s.%s", err.Error(), d.name, f.name)
+			}
+			obj.set(f.name, v)
+		}
+
+		if d.flags&javaSCWriteMethod != 0 {
+			contents, err := r.annotation()
+			if err != nil {
+				return obj, err
+			}
+
+			if len(contents) > 0 {
+				obj.set("@annotations", contents)
+			}
+		}
+	}
+
+	return obj, nil
+}
+
+func (r *javaReader) array() (interface{}, error) {
+	desc, err := r.classDesc()
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil || len(desc.name) < 2 || desc.name[0] != '[' {
+		return nil, fmt.Errorf("wrong class descriptor of array")
+	}
+
+	r.newHandle(nil)
+
+	n, err := r.count(4)
+	if err != nil {
+		return nil, err
+	}
+
+	// byte[] is shown as bytes
+	if desc.name == "[B" {
+		return r.bytes(n)
+	}
+
+	list := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := r.fieldValue(desc.name[1])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	return list, nil
+}
+
+// fieldValue read value by type code of field
+func (r *javaReader) fieldValue(typeCode byte) (interface{}, error) {
+	switch typeCode {
+	case 'B':
+		v, err := r.uint(1)
+		return int64(int8(v)), err
+	case 'C':
+		v, err := r.uint(2)
+		return string(rune(v)), err
+	case 'D':
+		v, err := r.uint(8)
+		return math.Float64frombits(v), err
+	case 'F':
+		v, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 'I':
+		v, err := r.uint(4)
+		return int64(int32(v)), err
+	case 'J':
+		v, err := r.uint(8)
+		return int64(v), err
+	case 'S':
+		v, err := r.uint(2)
+		return int64(int16(v)), err
+	case 'Z':
+		v, err := r.uint(1)
+		return v != 0, err
+	case 'L', '[':
+		return r.object()
+	}
+
+	return nil, fmt.Errorf("unknown field type %q", typeCode)
+}
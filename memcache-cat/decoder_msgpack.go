@@ -0,0 +1,222 @@
+package mccat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MessagePack format (https://github.com/msgpack/msgpack/blob/master/spec.md)
+
+const msgpackTimestamp = -1
+
+// isMsgpack check value starts with map or array. msgpack has no magic bytes,
+// and these types are not valid at the beginning of UTF-8 text
+func isMsgpack(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+
+	b := value[0]
+
+	return (0x80 <= b && b <= 0x9f) || (0xdc <= b && b <= 0xdf)
+}
+
+func decodeMsgpack(value []byte) (interface{}, error) {
+	r := &byteReader{data: value}
+
+	v, err := msgpackValue(r, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s at offset %d", err.Error(), r.pos)
+	}
+
+	if r.remain() > 0 {
+		return nil, fmt.Errorf("extra data at offset %d", r.pos)
+	}
+
+	return v, nil
+}
+
+func msgpackValue(r *byteReader, depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("too deep nested value")
+	}
+
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b <= 0x8f:
+		return msgpackMap(r, int(b&0x0f), depth)
+	case b <= 0x9f:
+		return msgpackArray(r, int(b&0x0f), depth)
+	case b <= 0xbf:
+		return msgpackString(r, int(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.count(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+
+		return r.bytes(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := r.count(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackExt(r, n)
+	case 0xca:
+		v, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		return float64(math.Float32frombits(uint32(v))), nil
+	case 0xcb:
+		v, err := r.uint(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(v), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return r.uint(1 << (b - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+
+		v, err := r.uint(size)
+		if err != nil {
+			return nil, err
+		}
+
+		// sign extension
+		shift := uint(64 - size*8)
+
+		return int64(v<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return msgpackExt(r, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.count(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := r.count(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := r.count(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackMap(r, n, depth)
+	}
+
+	return nil, fmt.Errorf("unknown type 0x%02x", b)
+}
+
+func msgpackString(r *byteReader, n int) (interface{}, error) {
+	b, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+func msgpackArray(r *byteReader, n int, depth int) (interface{}, error) {
+	if n > r.remain() {
+		return nil, errUnexpectedEnd
+	}
+
+	list := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := msgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	return list, nil
+}
+
+func msgpackMap(r *byteReader, n int, depth int) (interface{}, error) {
+	m := &orderedMap{}
+	for i := 0; i < n; i++ {
+		k, err := msgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := msgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		m.set(k, v)
+	}
+
+	return m, nil
+}
+
+// msgpackExt read extension type and data. timestamp extension is shown as RFC3339 time
+func msgpackExt(r *byteReader, n int) (interface{}, error) {
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(t) == msgpackTimestamp {
+		var sec int64
+		var nsec uint32
+
+		switch n {
+		case 4:
+			sec = int64(binary.BigEndian.Uint32(data))
+		case 8:
+			v := binary.BigEndian.Uint64(data)
+			nsec, sec = uint32(v>>34), int64(v&(1<<34-1))
+		case 12:
+			nsec, sec = binary.BigEndian.Uint32(data), int64(binary.BigEndian.Uint64(data[4:]))
+		default:
+			return nil, fmt.Errorf("wrong timestamp length %d", n)
+		}
+
+		return time.Unix(sec, int64(nsec)).UTC().Format(time.RFC3339Nano), nil
+	}
+
+	ext := &orderedMap{}
+	ext.set("@ext", int64(int8(t)))
+	ext.set("data", data)
+
+	return ext, nil
+}
@@ -0,0 +1,571 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PHP serialize() format (https://www.php.net/manual/en/function.serialize.php)
+// and igbinary extension format (https://github.com/igbinary/igbinary)
+
+var phpSerializedPattern = regexp.MustCompile(`^(a:\d+:\{|[OC]:\d+:"|s:\d+:"|E:\d+:"|i:-?\d+;$|d:[^;]+;$|b:[01];$|N;$)`)
+
+func isPHPSerialized(value []byte) bool {
+	return phpSerializedPattern.Match(value)
+}
+
+func decodePHP(value []byte) (interface{}, error) {
+	r := &byteReader{data: value}
+
+	v, err := phpValue(r, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s at offset %d", err.Error(), r.pos)
+	}
+
+	if r.remain() > 0 {
+		return nil, fmt.Errorf("extra data at offset %d", r.pos)
+	}
+
+	return v, nil
+}
+
+// phpUntil read text until delimiter and skip it
+func phpUntil(r *byteReader, delim byte) (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], delim)
+	if end < 0 {
+		return "", fmt.Errorf("missing %q", delim)
+	}
+
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+
+	return s, nil
+}
+
+func phpExpect(r *byteReader, s string) error {
+	b, err := r.bytes(len(s))
+	if err != nil || string(b) != s {
+		return fmt.Errorf("expected %q", s)
+	}
+
+	return nil
+}
+
+// phpString read `len:"text"` and return text
+func phpString(r *byteReader) (string, error) {
+	length, err := phpUntil(r, ':')
+	if err != nil {
+		return "", err
+	}
+
+	n, err := strconv.Atoi(length)
+	if err != nil {
+		return "", fmt.Errorf("wrong string length %s", length)
+	}
+
+	if err := phpExpect(r, `"`); err != nil {
+		return "", err
+	}
+
+	b, err := r.bytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	if err := phpExpect(r, `"`); err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func phpValue(r *byteReader, depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("too deep nested value")
+	}
+
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	if t == 'N' {
+		return nil, phpExpect(r, ";")
+	}
+
+	if err := phpExpect(r, ":"); err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case 'b':
+		s, err := phpUntil(r, ';')
+		if err != nil {
+			return nil, err
+		}
+
+		return s == "1", nil
+	case 'i':
+		s, err := phpUntil(r, ';')
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.ParseInt(s, 10, 64)
+	case 'd':
+		s, err := phpUntil(r, ';')
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.ParseFloat(s, 64)
+	case 's':
+		s, err := phpString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return s, phpExpect(r, ";")
+	case 'E':
+		// enum case (Suit:Hearts)
+		s, err := phpString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return strings.Replace(s, ":", "::", 1), phpExpect(r, ";")
+	case 'r', 'R':
+		// reference to n-th value is not expanded (it can refer same value many times)
+		s, err := phpUntil(r, ';')
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("wrong reference %s", s)
+		}
+
+		return fmt.Sprintf("@ref %d", n), nil
+	case 'a':
+		m, err := phpArray(r, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		if isList(m) {
+			return m.values, nil
+		}
+
+		return m, nil
+	case 'O':
+		class, err := phpString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := phpExpect(r, ":"); err != nil {
+			return nil, err
+		}
+
+		props, err := phpArray(r, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		return phpObject(class, props), nil
+	case 'C':
+		// object of class which implements Serializable (data is defined by class)
+		class, err := phpString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := phpExpect(r, ":"); err != nil {
+			return nil, err
+		}
+
+		// data is enclosed by {} instead of ""
+		length, err := phpUntil(r, ':')
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := strconv.Atoi(length)
+		if err != nil {
+			return nil, fmt.Errorf("wrong data length %s", length)
+		}
+
+		if err := phpExpect(r, "{"); err != nil {
+			return nil, err
+		}
+
+		data, err := r.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := phpExpect(r, "}"); err != nil {
+			return nil, err
+		}
+
+		obj := &orderedMap{}
+		obj.set("@class", class)
+		obj.set("@serialized", data)
+
+		return obj, nil
+	}
+
+	return nil, fmt.Errorf("unknown type %q", t)
+}
+
+// phpArray read `n:{key;value;...}`
+func phpArray(r *byteReader, depth int) (*orderedMap, error) {
+	s, err := phpUntil(r, ':')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > r.remain() {
+		return nil, fmt.Errorf("wrong array size %s", s)
+	}
+
+	if err := phpExpect(r, "{"); err != nil {
+		return nil, err
+	}
+
+	m := &orderedMap{}
+	for i := 0; i < n; i++ {
+		key, err := phpValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := phpValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		m.set(key, value)
+	}
+
+	return m, phpExpect(r, "}")
+}
+
+// phpObject make object with class name and properties.
+// name of protected and private property is shown like print_r (name:protected, name:Class:private)
+func phpObject(class string, props *orderedMap) *orderedMap {
+	obj := &orderedMap{}
+	obj.set("@class", class)
+
+	for i, k := range props.keys {
+		if name, ok := k.(string); ok && strings.HasPrefix(name, "\x00") {
+			parts := strings.SplitN(name[1:], "\x00", 2)
+			if len(parts) == 2 {
+				if parts[0] == "*" {
+					k = parts[1] + ":protected"
+				} else {
+					k = parts[1] + ":" + parts[0] + ":private"
+				}
+			}
+		}
+
+		obj.set(k, props.values[i])
+	}
+
+	return obj
+}
+
+// igbinary types
+const (
+	igNull         = 0x00
+	igRef8         = 0x01
+	igRef16        = 0x02
+	igRef32        = 0x03
+	igFalse        = 0x04
+	igTrue         = 0x05
+	igLong8p       = 0x06
+	igLong8n       = 0x07
+	igLong16p      = 0x08
+	igLong16n      = 0x09
+	igLong32p      = 0x0a
+	igLong32n      = 0x0b
+	igDouble       = 0x0c
+	igStringEmpty  = 0x0d
+	igStringID8    = 0x0e
+	igStringID16   = 0x0f
+	igStringID32   = 0x10
+	igString8      = 0x11
+	igString16     = 0x12
+	igString32     = 0x13
+	igArray8       = 0x14
+	igArray16      = 0x15
+	igArray32      = 0x16
+	igObject8      = 0x17
+	igObject16     = 0x18
+	igObject32     = 0x19
+	igObjectID8    = 0x1a
+	igObjectID16   = 0x1b
+	igObjectID32   = 0x1c
+	igObjectSer8   = 0x1d
+	igObjectSer16  = 0x1e
+	igObjectSer32  = 0x1f
+	igLong64p      = 0x20
+	igLong64n      = 0x21
+	igObjRef8      = 0x22
+	igObjRef16     = 0x23
+	igObjRef32     = 0x24
+	igSimpleRef    = 0x25
+	igbinaryHeader = 4
+)
+
+// igbinaryReader keep strings table for string id
+type igbinaryReader struct {
+	byteReader
+	strings []string
+}
+
+func isIgbinary(value []byte) bool {
+	if len(value) <= igbinaryHeader {
+		return false
+	}
+
+	version := binary.BigEndian.Uint32(value)
+
+	return version == 1 || version == 2
+}
+
+func decodeIgbinary(value []byte) (interface{}, error) {
+	if !isIgbinary(value) {
+		return nil, fmt.Errorf("unknown igbinary header")
+	}
+
+	r := &igbinaryReader{byteReader: byteReader{data: value, pos: igbinaryHeader}}
+
+	v, err := r.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("%s at offset %d", err.Error(), r.pos)
+	}
+
+	if r.remain() > 0 {
+		return nil, fmt.Errorf("extra data at offset %d", r.pos)
+	}
+
+	return v, nil
+}
+
+// sizeOf return byte size of length field of 8, 16, 32 variant types (first is type of 8bit variant)
+func sizeOf(t, first byte) int {
+	return 1 << (t - first)
+}
+
+func (r *igbinaryReader) value(depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, fmt.Errorf("too deep nested value")
+	}
+
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case igNull:
+		return nil, nil
+	case igFalse:
+		return false, nil
+	case igTrue:
+		return true, nil
+	case igLong8p, igLong8n, igLong16p, igLong16n, igLong32p, igLong32n, igLong64p, igLong64n:
+		return r.long(t)
+	case igDouble:
+		v, err := r.uint(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(v), nil
+	case igStringEmpty, igStringID8, igStringID16, igStringID32, igString8, igString16, igString32:
+		return r.string(t)
+	case igArray8, igArray16, igArray32:
+		m, err := r.array(t, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		if isList(m) {
+			return m.values, nil
+		}
+
+		return m, nil
+	case igObject8, igObject16, igObject32, igObjectID8, igObjectID16, igObjectID32:
+		return r.object(t, depth)
+	case igRef8, igRef16, igRef32:
+		n, err := r.uint(sizeOf(t, igRef8))
+		if err != nil {
+			return nil, err
+		}
+
+		return fmt.Sprintf("@ref %d", n), nil
+	case igObjRef8, igObjRef16, igObjRef32:
+		n, err := r.uint(sizeOf(t, igObjRef8))
+		if err != nil {
+			return nil, err
+		}
+
+		return fmt.Sprintf("@ref object %d", n), nil
+	case igSimpleRef:
+		return r.value(depth + 1)
+	}
+
+	return nil, fmt.Errorf("unknown type 0x%02x", t)
+}
+
+func (r *igbinaryReader) long(t byte) (interface{}, error) {
+	var size int
+
+	switch t {
+	case igLong8p, igLong8n:
+		size = 1
+	case igLong16p, igLong16n:
+		size = 2
+	case igLong32p, igLong32n:
+		size = 4
+	default:
+		size = 8
+	}
+
+	negative := t == igLong8n || t == igLong16n || t == igLong32n || t == igLong64n
+
+	v, err := r.uint(size)
+	if err != nil {
+		return nil, err
+	}
+
+	if negative {
+		return -int64(v), nil
+	}
+
+	return int64(v), nil
+}
+
+func (r *igbinaryReader) string(t byte) (string, error) {
+	switch t {
+	case igStringEmpty:
+		return "", nil
+	case igStringID8, igStringID16, igStringID32:
+		id, err := r.uint(sizeOf(t, igStringID8))
+		if err != nil {
+			return "", err
+		}
+		if id >= uint64(len(r.strings)) {
+			return "", fmt.Errorf("wrong string id %d", id)
+		}
+
+		return r.strings[id], nil
+	case igString8, igString16, igString32:
+		n, err := r.count(sizeOf(t, igString8))
+		if err != nil {
+			return "", err
+		}
+
+		b, err := r.bytes(n)
+		if err != nil {
+			return "", err
+		}
+
+		r.strings = append(r.strings, string(b))
+
+		return string(b), nil
+	}
+
+	return "", fmt.Errorf("type 0x%02x is not string", t)
+}
+
+func (r *igbinaryReader) array(t byte, depth int) (*orderedMap, error) {
+	n, err := r.count(sizeOf(t, igArray8))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &orderedMap{}
+	for i := 0; i < n; i++ {
+		kt, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+
+		var key interface{}
+		switch kt {
+		case igLong8p, igLong8n, igLong16p, igLong16n, igLong32p, igLong32n, igLong64p, igLong64n:
+			key, err = r.long(kt)
+		default:
+			key, err = r.string(kt)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := r.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		m.set(key, value)
+	}
+
+	return m, nil
+}
+
+func (r *igbinaryReader) object(t byte, depth int) (interface{}, error) {
+	var class string
+	var err error
+
+	if t <= igObject32 {
+		class, err = r.string(t - igObject8 + igString8)
+	} else {
+		class, err = r.string(t - igObjectID8 + igStringID8)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pt, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch pt {
+	case igArray8, igArray16, igArray32:
+		props, err := r.array(pt, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		return phpObject(class, props), nil
+	case igObjectSer8, igObjectSer16, igObjectSer32:
+		n, err := r.count(sizeOf(pt, igObjectSer8))
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := r.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+
+		obj := &orderedMap{}
+		obj.set("@class", class)
+		obj.set("@serialized", data)
+
+		return obj, nil
+	}
+
+	return nil, fmt.Errorf("unknown type 0x%02x of object %s", pt, class)
+}
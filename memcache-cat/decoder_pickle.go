@@ -0,0 +1,925 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Python pickle format (https://github.com/python/cpython/blob/main/Lib/pickletools.py).
+// only data is read. classes are never imported and functions are never called,
+// they are shown as class name with arguments and state instead
+
+const (
+	pickleMark           = '('
+	pickleStop           = '.'
+	picklePop            = '0'
+	picklePopMark        = '1'
+	pickleDup            = '2'
+	pickleFloat          = 'F'
+	pickleInt            = 'I'
+	pickleBinInt         = 'J'
+	pickleBinInt1        = 'K'
+	pickleLong           = 'L'
+	pickleBinInt2        = 'M'
+	pickleNone           = 'N'
+	picklePersID         = 'P'
+	pickleBinPersID      = 'Q'
+	pickleReduce         = 'R'
+	pickleString         = 'S'
+	pickleBinString      = 'T'
+	pickleShortBinString = 'U'
+	pickleUnicode        = 'V'
+	pickleBinUnicode     = 'X'
+	pickleAppend         = 'a'
+	pickleBuild          = 'b'
+	pickleGlobal         = 'c'
+	pickleDict           = 'd'
+	pickleEmptyDict      = '}'
+	pickleAppends        = 'e'
+	pickleGet            = 'g'
+	pickleBinGet         = 'h'
+	pickleInst           = 'i'
+	pickleLongBinGet     = 'j'
+	pickleList           = 'l'
+	pickleEmptyList      = ']'
+	pickleObj            = 'o'
+	picklePut            = 'p'
+	pickleBinPut         = 'q'
+	pickleLongBinPut     = 'r'
+	pickleSetItem        = 's'
+	pickleTuple          = 't'
+	pickleEmptyTuple     = ')'
+	pickleSetItems       = 'u'
+	pickleBinFloat       = 'G'
+	pickleProto          = 0x80
+	pickleNewObj         = 0x81
+	pickleTuple1         = 0x85
+	pickleTuple2         = 0x86
+	pickleTuple3         = 0x87
+	pickleNewTrue        = 0x88
+	pickleNewFalse       = 0x89
+	pickleLong1          = 0x8a
+	pickleLong4          = 0x8b
+	pickleBinBytes       = 'B'
+	pickleShortBinBytes  = 'C'
+	pickleShortBinUni    = 0x8c
+	pickleBinUnicode8    = 0x8d
+	pickleBinBytes8      = 0x8e
+	pickleEmptySet       = 0x8f
+	pickleAddItems       = 0x90
+	pickleFrozenSet      = 0x91
+	pickleNewObjEx       = 0x92
+	pickleStackGlobal    = 0x93
+	pickleMemoize        = 0x94
+	pickleFrame          = 0x95
+	pickleByteArray8     = 0x96
+)
+
+// pyList, pyDict and pyObject are mutable while reading pickle (memo refers same object)
+type pyList struct {
+	items []interface{}
+}
+
+type pyDict struct {
+	m orderedMap
+}
+
+type pyGlobal struct {
+	name string
+}
+
+// pyObject is result of class call. list and dict are items of subclass of list and dict
+// (APPENDS and SETITEMS after REDUCE)
+type pyObject struct {
+	class string
+	args  []interface{}
+	state interface{}
+	list  *pyList
+	dict  *pyDict
+}
+
+// pyMark is placed on stack by MARK
+type pyMark struct{}
+
+func isPickle(value []byte) bool {
+	if len(value) < 3 || value[len(value)-1] != pickleStop {
+		return false
+	}
+
+	// protocol 2 or later starts with PROTO. protocol 0 and 1 usually start with container
+	switch value[0] {
+	case pickleProto:
+		return value[1] <= 5
+	case pickleMark, pickleEmptyDict, pickleEmptyList, pickleEmptyTuple:
+		return true
+	}
+
+	return false
+}
+
+func decodePickle(value []byte) (interface{}, error) {
+	u := &unpickler{r: byteReader{data: value}, memo: map[uint64]interface{}{}}
+
+	v, err := u.load()
+	if err != nil {
+		return nil, fmt.Errorf("%s at offset %d", err.Error(), u.r.pos)
+	}
+
+	if u.r.remain() > 0 {
+		return nil, fmt.Errorf("extra data at offset %d", u.r.pos)
+	}
+
+	p := &pyConverter{path: map[interface{}]bool{}, done: map[interface{}]interface{}{}, memo: map[interface{}]uint64{}}
+
+	for id, m := range u.memo {
+		if key, ok := pyIdentity(m); ok {
+			if prev, ok := p.memo[key]; !ok || id < prev {
+				p.memo[key] = id
+			}
+		}
+	}
+
+	return p.value(v), nil
+}
+
+type unpickler struct {
+	r     byteReader
+	stack []interface{}
+	memo  map[uint64]interface{}
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+
+	v := u.stack[len(u.stack)-1]
+	u.stack = u.stack[:len(u.stack)-1]
+
+	return v, nil
+}
+
+func (u *unpickler) top() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+
+	return u.stack[len(u.stack)-1], nil
+}
+
+// popMark pop items until MARK and return them
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(pyMark); ok {
+			items := append([]interface{}{}, u.stack[i+1:]...)
+			u.stack = u.stack[:i]
+
+			return items, nil
+		}
+	}
+
+	return nil, fmt.Errorf("mark not found")
+}
+
+// line read text until line break (used by text opcodes of protocol 0)
+func (u *unpickler) line() (string, error) {
+	end := bytes.IndexByte(u.r.data[u.r.pos:], '\n')
+	if end < 0 {
+		return "", errUnexpectedEnd
+	}
+
+	s := string(u.r.data[u.r.pos : u.r.pos+end])
+	u.r.pos += end + 1
+
+	return s, nil
+}
+
+// uintLE read n bytes little endian unsigned integer
+func (u *unpickler) uintLE(n int) (uint64, error) {
+	b, err := u.r.bytes(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+
+	return v, nil
+}
+
+// sized read n bytes little endian length and data
+func (u *unpickler) sized(n int) ([]byte, error) {
+	size, err := u.uintLE(n)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(u.r.remain()) {
+		return nil, errUnexpectedEnd
+	}
+
+	return u.r.bytes(int(size))
+}
+
+func (u *unpickler) load() (interface{}, error) {
+	for {
+		op, err := u.r.byte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case pickleStop:
+			return u.pop()
+		case pickleProto:
+			if _, err := u.r.byte(); err != nil {
+				return nil, err
+			}
+		case pickleFrame:
+			if _, err := u.r.bytes(8); err != nil {
+				return nil, err
+			}
+		case pickleMark:
+			u.push(pyMark{})
+		case picklePop:
+			if _, err := u.pop(); err != nil {
+				return nil, err
+			}
+		case picklePopMark:
+			if _, err := u.popMark(); err != nil {
+				return nil, err
+			}
+		case pickleDup:
+			v, err := u.top()
+			if err != nil {
+				return nil, err
+			}
+			u.push(v)
+		case pickleNone:
+			u.push(nil)
+		case pickleNewTrue:
+			u.push(true)
+		case pickleNewFalse:
+			u.push(false)
+		case pickleInt:
+			s, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+
+			// protocol 0 writes bool as I01 and I00
+			switch s {
+			case "01":
+				u.push(true)
+			case "00":
+				u.push(false)
+			default:
+				n, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				u.push(n)
+			}
+		case pickleLong:
+			s, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+
+			n, ok := new(big.Int).SetString(strings.TrimSuffix(s, "L"), 10)
+			if !ok {
+				return nil, fmt.Errorf("wrong long %s", s)
+			}
+			u.push(n)
+		case pickleBinInt:
+			v, err := u.uintLE(4)
+			if err != nil {
+				return nil, err
+			}
+			u.push(int64(int32(v)))
+		case pickleBinInt1:
+			v, err := u.uintLE(1)
+			if err != nil {
+				return nil, err
+			}
+			u.push(int64(v))
+		case pickleBinInt2:
+			v, err := u.uintLE(2)
+			if err != nil {
+				return nil, err
+			}
+			u.push(int64(v))
+		case pickleLong1, pickleLong4:
+			size := 1
+			if op == pickleLong4 {
+				size = 4
+			}
+
+			b, err := u.sized(size)
+			if err != nil {
+				return nil, err
+			}
+			u.push(pickleLongValue(b))
+		case pickleFloat:
+			s, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, err
+			}
+			u.push(f)
+		case pickleBinFloat:
+			b, err := u.r.bytes(8)
+			if err != nil {
+				return nil, err
+			}
+			u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+		case pickleString:
+			s, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+			u.push(unquotePython(s))
+		case pickleUnicode:
+			s, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+			u.push(unescapeRawUnicode(s))
+		case pickleBinString, pickleShortBinString, pickleBinBytes, pickleShortBinBytes, pickleBinBytes8, pickleByteArray8:
+			// python 2 str is bytes
+			size := 4
+			switch op {
+			case pickleShortBinString, pickleShortBinBytes:
+				size = 1
+			case pickleBinBytes8, pickleByteArray8:
+				size = 8
+			}
+
+			b, err := u.sized(size)
+			if err != nil {
+				return nil, err
+			}
+			u.push(b)
+		case pickleBinUnicode, pickleShortBinUni, pickleBinUnicode8:
+			size := 4
+			switch op {
+			case pickleShortBinUni:
+				size = 1
+			case pickleBinUnicode8:
+				size = 8
+			}
+
+			b, err := u.sized(size)
+			if err != nil {
+				return nil, err
+			}
+			u.push(string(b))
+		case pickleEmptyList:
+			u.push(&pyList{})
+		case pickleEmptyTuple:
+			u.push([]interface{}{})
+		case pickleEmptyDict:
+			u.push(&pyDict{})
+		case pickleEmptySet:
+			u.push(&pyList{})
+		case pickleList, pickleTuple, pickleFrozenSet:
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+
+			if op == pickleList {
+				u.push(&pyList{items: items})
+			} else {
+				u.push(items)
+			}
+		case pickleTuple1, pickleTuple2, pickleTuple3:
+			n := int(op-pickleTuple1) + 1
+			if len(u.stack) < n {
+				return nil, fmt.Errorf("stack underflow")
+			}
+
+			items := append([]interface{}{}, u.stack[len(u.stack)-n:]...)
+			u.stack = u.stack[:len(u.stack)-n]
+			u.push(items)
+		case pickleDict:
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+
+			d := &pyDict{}
+			for i := 0; i+1 < len(items); i += 2 {
+				d.m.set(items[i], items[i+1])
+			}
+			u.push(d)
+		case pickleAppend:
+			v, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+			if err := u.appendItems([]interface{}{v}); err != nil {
+				return nil, err
+			}
+		case pickleAppends, pickleAddItems:
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err := u.appendItems(items); err != nil {
+				return nil, err
+			}
+		case pickleSetItem:
+			v, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			k, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := u.setItems([]interface{}{k, v}); err != nil {
+				return nil, err
+			}
+		case pickleSetItems:
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err := u.setItems(items); err != nil {
+				return nil, err
+			}
+		case pickleGet, pickleBinGet, pickleLongBinGet:
+			id, err := u.memoID(op == pickleGet, op == pickleBinGet)
+			if err != nil {
+				return nil, err
+			}
+
+			v, ok := u.memo[id]
+			if !ok {
+				return nil, fmt.Errorf("memo %d not found", id)
+			}
+			u.push(v)
+		case picklePut, pickleBinPut, pickleLongBinPut, pickleMemoize:
+			var id uint64
+			if op == pickleMemoize {
+				id = uint64(len(u.memo))
+			} else if id, err = u.memoID(op == picklePut, op == pickleBinPut); err != nil {
+				return nil, err
+			}
+
+			v, err := u.top()
+			if err != nil {
+				return nil, err
+			}
+			u.memo[id] = v
+		case pickleGlobal:
+			module, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+
+			name, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+			u.push(&pyGlobal{name: module + "." + name})
+		case pickleStackGlobal:
+			name, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			module, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+			u.push(&pyGlobal{name: fmt.Sprintf("%v.%v", module, name)})
+		case pickleReduce, pickleNewObj:
+			args, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			class, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+			u.push(newPyObject(class, args))
+		case pickleNewObjEx:
+			kwargs, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			args, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			class, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			obj := newPyObject(class, args)
+			obj.state = kwargs
+			u.push(obj)
+		case pickleInst:
+			module, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+
+			name, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+
+			args, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			u.push(&pyObject{class: module + "." + name, args: args})
+		case pickleObj:
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if len(items) == 0 {
+				return nil, fmt.Errorf("class of OBJ not found")
+			}
+			u.push(newPyObject(items[0], items[1:]))
+		case pickleBuild:
+			state, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := u.top()
+			if err != nil {
+				return nil, err
+			}
+
+			obj, ok := v.(*pyObject)
+			if !ok {
+				return nil, fmt.Errorf("BUILD for non object")
+			}
+			obj.state = state
+		case picklePersID:
+			id, err := u.line()
+			if err != nil {
+				return nil, err
+			}
+			u.push(&pyObject{class: "persistent_id", args: []interface{}{id}})
+		case pickleBinPersID:
+			id, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+			u.push(&pyObject{class: "persistent_id", args: []interface{}{id}})
+		default:
+			return nil, fmt.Errorf("unsupported opcode 0x%02x", op)
+		}
+	}
+}
+
+// memoID read index of memo (text line of protocol 0, 1 byte or 4 bytes)
+func (u *unpickler) memoID(text bool, short bool) (uint64, error) {
+	if text {
+		s, err := u.line()
+		if err != nil {
+			return 0, err
+		}
+
+		return strconv.ParseUint(s, 10, 64)
+	}
+
+	if short {
+		return u.uintLE(1)
+	}
+
+	return u.uintLE(4)
+}
+
+func (u *unpickler) appendItems(items []interface{}) error {
+	v, err := u.top()
+	if err != nil {
+		return err
+	}
+
+	list, ok := v.(*pyList)
+	if obj, isObj := v.(*pyObject); isObj {
+		if obj.list == nil {
+			obj.list = &pyList{}
+		}
+		list, ok = obj.list, true
+	}
+	if !ok {
+		return fmt.Errorf("append to non list")
+	}
+	list.items = append(list.items, items...)
+
+	return nil
+}
+
+func (u *unpickler) setItems(items []interface{}) error {
+	v, err := u.top()
+	if err != nil {
+		return err
+	}
+
+	d, ok := v.(*pyDict)
+	if obj, isObj := v.(*pyObject); isObj {
+		if obj.dict == nil {
+			obj.dict = &pyDict{}
+		}
+		d, ok = obj.dict, true
+	}
+	if !ok {
+		return fmt.Errorf("set item to non dict")
+	}
+
+	for i := 0; i+1 < len(items); i += 2 {
+		d.m.set(items[i], items[i+1])
+	}
+
+	return nil
+}
+
+func newPyObject(class interface{}, args interface{}) *pyObject {
+	obj := &pyObject{class: fmt.Sprint(class)}
+	if g, ok := class.(*pyGlobal); ok {
+		obj.class = g.name
+	}
+
+	if a, ok := args.([]interface{}); ok {
+		obj.args = a
+	} else if args != nil {
+		obj.args = []interface{}{args}
+	}
+
+	return obj
+}
+
+// pickleLongValue convert little endian two's complement bytes to integer
+func pickleLongValue(b []byte) interface{} {
+	if len(b) == 0 {
+		return int64(0)
+	}
+
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+
+	n := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+
+	if n.IsInt64() {
+		return n.Int64()
+	}
+
+	return n
+}
+
+// unquotePython remove quotes of python 2 str repr
+func unquotePython(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		inner := strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`)
+		if u, err := strconv.Unquote(`"` + inner + `"`); err == nil {
+			return u
+		}
+
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// maxPickleNodes is limit of rendered values because memo can share same object many times
+const maxPickleNodes = 1 << 20
+
+// unescapeRawUnicode decode \uXXXX and \UXXXXXXXX of raw-unicode-escape (UNICODE opcode).
+// other bytes are latin-1 characters
+func unescapeRawUnicode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == 'u' || s[i+1] == 'U') {
+			n := 4
+			if s[i+1] == 'U' {
+				n = 8
+			}
+
+			if i+2+n <= len(s) {
+				if r, err := strconv.ParseUint(s[i+2:i+2+n], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 1 + n
+					continue
+				}
+			}
+		}
+
+		b.WriteRune(rune(s[i]))
+	}
+
+	return b.String()
+}
+
+// pyIdentity return key of container which can be shared by memo (list, dict, object and tuple)
+func pyIdentity(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case *pyList, *pyDict, *pyObject:
+		return t, true
+	case []interface{}:
+		// tuple is identified by its backing array
+		if len(t) > 0 {
+			return &t[0], true
+		}
+	}
+
+	return nil, false
+}
+
+// pyConverter convert values of unpickler to values for render.
+// path has containers of current position for detect recursive reference, and container which is
+// already converted is shown as @ref <memo index> (memo can share same container many times)
+type pyConverter struct {
+	path  map[interface{}]bool
+	done  map[interface{}]interface{}
+	memo  map[interface{}]uint64
+	nodes int
+}
+
+func (p *pyConverter) value(v interface{}) interface{} {
+	p.nodes++
+	if p.nodes > maxPickleNodes || len(p.path) > maxDecodeDepth {
+		return "..."
+	}
+
+	key, ok := pyIdentity(v)
+	if !ok {
+		return p.convert(v)
+	}
+
+	if p.path[key] {
+		return "<recursion>"
+	}
+
+	if converted, ok := p.done[key]; ok {
+		// simple values (ex: datetime, bytes) are shown again
+		switch converted.(type) {
+		case []interface{}, *orderedMap:
+			return p.ref(key)
+		}
+
+		return converted
+	}
+
+	p.path[key] = true
+	converted := p.convert(v)
+	delete(p.path, key)
+
+	p.done[key] = converted
+
+	return converted
+}
+
+// ref return back-reference to container which is already shown
+func (p *pyConverter) ref(key interface{}) string {
+	if id, ok := p.memo[key]; ok {
+		return fmt.Sprintf("@ref %d", id)
+	}
+
+	return "@ref"
+}
+
+func (p *pyConverter) convert(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *pyList:
+		return p.values(t.items)
+	case []interface{}:
+		return p.values(t)
+	case *pyDict:
+		m := &orderedMap{}
+		for i, k := range t.m.keys {
+			m.set(p.value(k), p.value(t.m.values[i]))
+		}
+
+		return m
+	case *pyGlobal:
+		return t.name
+	case *pyObject:
+		return p.object(t)
+	}
+
+	return v
+}
+
+func (p *pyConverter) values(items []interface{}) []interface{} {
+	list := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		list = append(list, p.value(item))
+	}
+
+	return list
+}
+
+// object show object as class name with arguments and attributes.
+// some standard types are shown as simple value
+func (p *pyConverter) object(obj *pyObject) interface{} {
+	args := p.values(obj.args)
+
+	switch obj.class {
+	case "__builtin__.set", "builtins.set", "__builtin__.frozenset", "builtins.frozenset":
+		if len(args) == 1 {
+			return args[0]
+		}
+	case "decimal.Decimal":
+		if len(args) == 1 {
+			return fmt.Sprint(args[0])
+		}
+	case "_codecs.encode":
+		// bytes of protocol 2 are written as encode(text, "latin1")
+		if len(args) == 2 && args[1] == "latin1" {
+			if text, ok := args[0].(string); ok {
+				b := make([]byte, 0, len(text))
+				for _, r := range text {
+					b = append(b, byte(r))
+				}
+
+				return b
+			}
+		}
+	case "datetime.datetime", "datetime.date":
+		if len(args) > 0 {
+			if b, ok := args[0].([]byte); ok {
+				if t, ok := pickleDateTime(b); ok {
+					return t
+				}
+			}
+		}
+	case "collections.OrderedDict", "collections.defaultdict":
+		if obj.dict != nil {
+			return p.value(obj.dict)
+		}
+	}
+
+	m := &orderedMap{}
+	m.set("@class", obj.class)
+
+	if len(args) > 0 {
+		m.set("@args", args)
+	}
+
+	switch state := p.value(obj.state).(type) {
+	case nil:
+	case *orderedMap:
+		for i, k := range state.keys {
+			m.set(k, state.values[i])
+		}
+	default:
+		m.set("@state", state)
+	}
+
+	if obj.list != nil {
+		m.set("@items", p.value(obj.list))
+	}
+	if obj.dict != nil {
+		m.set("@items", p.value(obj.dict))
+	}
+
+	return m
+}
+
+// pickleDateTime format state bytes of datetime (10 bytes) and date (4 bytes)
+func pickleDateTime(b []byte) (string, bool) {
+	switch len(b) {
+	case 4:
+		return fmt.Sprintf("%04d-%02d-%02d", int(b[0])<<8|int(b[1]), b[2], b[3]), true
+	case 10:
+		usec := int(b[7])<<16 | int(b[8])<<8 | int(b[9])
+
+		return fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d.%06d", int(b[0])<<8|int(b[1]), b[2], b[3], b[4], b[5], b[6], usec), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,240 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// compactDecoded render decoded value as JSON without indent
+func compactDecoded(v interface{}) string {
+	w := &decodedWriter{}
+	w.value(v, 0)
+
+	return w.buf.String()
+}
+
+func hexValue(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+var decoderTests = []struct {
+	name   string
+	format string
+	// value is hex of value, or text when it starts with !
+	value string
+	want  string
+}{
+	{"json object", "json", `!{"b":[1,2.5,"x",null,true],"a":{}}`, `{"b":[1,2.5,"x",null,true],"a":{}}`},
+	{"php array", "php", `!a:2:{s:4:"name";s:5:"mccat";s:4:"tags";a:2:{i:0;s:9:"memcached";i:1;s:3:"cli";}}`, `{"name":"mccat","tags":["memcached","cli"]}`},
+	{"php object", "php", "!O:4:\"User\":3:{s:2:\"id\";i:1;s:7:\"\x00*\x00role\";s:5:\"admin\";s:8:\"\x00User\x00pw\";N;}", `{"@class":"User","id":1,"role:protected":"admin","pw:User:private":null}`},
+	{"php scalars", "php", `!a:4:{i:0;b:1;i:1;d:0.5;i:2;i:-7;i:3;E:8:"Suit:Red";}`, `[true,0.5,-7,"Suit::Red"]`},
+	{"php reference", "php", `!a:2:{i:0;a:1:{i:0;i:1;}i:1;R:2;}`, `[[1],"@ref 2"]`},
+	{"igbinary array", "igbinary", "00000002 1402 11046e616d65 11056d63636174 110474616773 1402 0600 11096d656d636163686564 0601 1103636c69", `{"name":"mccat","tags":["memcached","cli"]}`},
+	{"igbinary string id", "igbinary", "00000002 1403 0600 110161 0601 0e00 0602 0701", `["a","a",-1]`},
+	{"pickle protocol 0", "pickle", "286470300a566e616d650a70310a566d636361740a70320a7356746167730a70330a286c70340a566d656d6361636865640a70350a6156636c690a70360a6173566e0a70370a492d330a7356660a70380a46312e350a73566e6f6e650a70390a4e73566f6b0a7031300a4930310a7356740a7031310a2849310a49320a747031320a73566269670a7031330a4c313138303539313632303731373431313330333432344c0a7356620a7031340a635f636f646563730a656e636f64650a7031350a28565c7530303030ff0a7031360a566c6174696e310a7031370a747031380a527031390a732e", `{"name":"mccat","tags":["memcached","cli"],"n":-3,"f":1.5,"none":null,"ok":true,"t":[1,2],"big":1180591620717411303424,"b":"0x00ff"}`},
+	{"pickle protocol 2", "pickle", "80027d71002858040000006e616d65710158050000006d63636174710258040000007461677371035d71042858090000006d656d63616368656471055803000000636c6971066558010000006e71074afdffffff5801000000667108473ff800000000000058040000006e6f6e6571094e58020000006f6b710a88580100000074710b4b014b0286710c5803000000626967710d8a09000000000000000040580100000062710e635f636f646563730a656e636f64650a710f580300000000c3bf711058060000006c6174696e317111867112527113752e", `{"name":"mccat","tags":["memcached","cli"],"n":-3,"f":1.5,"none":null,"ok":true,"t":[1,2],"big":1180591620717411303424,"b":"0x00ff"}`},
+	{"pickle protocol 5", "pickle", "80059579000000000000007d94288c046e616d65948c056d63636174948c0474616773945d94288c096d656d636163686564948c03636c6994658c016e944afdffffff8c016694473ff80000000000008c046e6f6e65944e8c026f6b94888c0174944b014b0286948c03626967948a090000000000000000408c016294430200ff94752e", `{"name":"mccat","tags":["memcached","cli"],"n":-3,"f":1.5,"none":null,"ok":true,"t":[1,2],"big":1180591620717411303424,"b":"0x00ff"}`},
+	{"pickle ordered dict", "pickle", "800263636f6c6c656374696f6e730a4f726465726564446963740a71002952710158010000006171024b01732e", `{"a":1}`},
+	{"pickle datetime", "pickle", "8002636461746574696d650a6461746574696d650a7100635f636f646563730a656e636f64650a7101580b00000007c3a80102030405000006710258060000006c6174696e3171038671045271058571065271072e", `"2024-01-02T03:04:05.000006"`},
+	{"pickle shared list", "pickle", "80025d7100285d71014b01616801652e", `[[1],"@ref 1"]`},
+	{"pickle recursive list", "pickle", "80025d71006800612e", `["<recursion>"]`},
+	{"java object", "java", "aced0005 7372 0004 54657374 0000000000000001 02 0001 49 0001 78 78 70 00000005", `{"@class":"Test","@serialVersionUID":1,"x":5}`},
+	{"java string", "java", "aced0005 740005 68656c6c6f", `"hello"`},
+	{"java class", "java", "aced0005 76 7200045465737400000000000000010200007870", `"class Test"`},
+	{"java enum", "java", "aced0005 7e 7200054c6576656c00000000000000001200007872000e6a6176612e6c616e672e456e756d00000000000000001200007870 74000448494748", `"Level.HIGH"`},
+	{"cbor map", "cbor", "a2 646e616d65 656d63636174 6474616773 82 696d656d636163686564 63636c69", `{"name":"mccat","tags":["memcached","cli"]}`},
+	{"cbor scalars", "cbor", "a1 6176 86 01 20 f93e00 f5 f6 4200ff", `{"v":[1,-1,1.5,true,null,"0x00ff"]}`},
+	{"msgpack map", "msgpack", "82 a46e616d65 a56d63636174 a474616773 92 a96d656d636163686564 a3636c69", `{"name":"mccat","tags":["memcached","cli"]}`},
+	{"msgpack scalars", "msgpack", "96 01 ff cb3ff8000000000000 c3 c0 c40200ff", `[1,-1,1.5,true,null,"0x00ff"]`},
+}
+
+func decoderTestValue(t *testing.T, value string) []byte {
+	if strings.HasPrefix(value, "!") {
+		return []byte(value[1:])
+	}
+
+	return hexValue(t, value)
+}
+
+func TestDecoders(t *testing.T) {
+	for _, tt := range decoderTests {
+		d := findDecoder(tt.format)
+		if d == nil {
+			t.Fatalf("%s: decoder %s not found", tt.name, tt.format)
+		}
+
+		v, err := d.decode(decoderTestValue(t, tt.value))
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+
+		got := compactDecoded(v)
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+
+		// rendered value is same as json.Indent
+		rendered, err := renderDecoded(v)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, []byte(got), "", "  "); err != nil {
+			t.Errorf("%s: wrong JSON %s: %s", tt.name, got, err)
+		} else if indented.String() != string(rendered) {
+			t.Errorf("%s: indent is different:\n%s\n%s", tt.name, rendered, indented.String())
+		}
+	}
+}
+
+func TestDecodeAuto(t *testing.T) {
+	for _, tt := range decoderTests {
+		_, format, err := decodeValue(decoderTestValue(t, tt.value), decodeAuto)
+		if err != nil || format != tt.format {
+			t.Errorf("%s: detected as %q (%v), want %s", tt.name, format, err, tt.format)
+		}
+	}
+
+	value := []byte("plain text")
+	if got, format, err := decodeValue(value, decodeAuto); err != nil || format != "" || !bytes.Equal(got, value) {
+		t.Errorf("plain text is decoded as %q: %s (%v)", format, got, err)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	for _, tt := range decoderTests {
+		value := decoderTestValue(t, tt.value)
+		d := findDecoder(tt.format)
+
+		for n := 0; n < len(value); n++ {
+			v, err := d.decode(value[:n])
+			// java decoder show parsed part with @error instead of error
+			if err == nil && !(tt.format == "java" && strings.Contains(compactDecoded(v), `"@error: `)) {
+				t.Errorf("%s: %d of %d bytes is decoded as %s", tt.name, n, len(value), compactDecoded(v))
+			}
+		}
+	}
+}
+
+func TestDecodeJavaNullClassDesc(t *testing.T) {
+	values := []string{
+		// super class of object is class with null descriptor
+		"aced0005 7372 0004 54657374 0000000000000001 02 0001 49 0001 78 78 76 70 00000005",
+		"aced0005 7372 0004 54657374 0000000000000001 02 0001 49 0001 78 76 70 00000005",
+		"aced0005 76 70",
+		"aced0005 7e 70 74000448494748",
+	}
+
+	for _, value := range values {
+		for _, format := range []string{"java", decodeAuto} {
+			out, _, err := decodeValue(hexValue(t, value), format)
+			if err == nil && !bytes.Contains(out, []byte("@error: ")) {
+				t.Errorf("%s is decoded by %s: %s", value, format, out)
+			}
+		}
+	}
+}
+
+func TestDecodeSharedReferences(t *testing.T) {
+	// each level is list (or tuple) which has previous level twice
+	// ] ( h i-1 h i-1 e q i
+	sharedLists := []byte{pickleProto, 2, pickleEmptyList, pickleBinPut, 0}
+	sharedTuples := []byte{pickleProto, 2, pickleEmptyList, pickleBinPut, 0}
+	for i := 1; i <= 250; i++ {
+		sharedLists = append(sharedLists, pickleEmptyList, pickleMark, pickleBinGet, byte(i-1), pickleBinGet, byte(i-1), pickleAppends, pickleBinPut, byte(i))
+		sharedTuples = append(sharedTuples, pickleBinGet, byte(i-1), pickleBinGet, byte(i-1), pickleTuple2, pickleBinPut, byte(i))
+	}
+	sharedLists = append(sharedLists, pickleStop)
+	sharedTuples = append(sharedTuples, pickleStop)
+
+	// each level is array which has previous level and reference to it
+	php := "i:1;"
+	for i := 0; i < 250; i++ {
+		php = "a:2:{i:0;" + php + "i:1;r:2;}"
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		value  []byte
+	}{
+		{"pickle shared lists", "pickle", sharedLists},
+		{"pickle shared tuples", "pickle", sharedTuples},
+		{"php references", "php", []byte(php)},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{tt.format, decodeAuto} {
+			start := time.Now()
+
+			out, got, err := decodeValue(tt.value, format)
+			if err != nil || got != tt.format {
+				t.Errorf("%s: decoded as %q by %s: %v", tt.name, got, format, err)
+				continue
+			}
+			if len(out) > 1<<20 {
+				t.Errorf("%s: %d bytes of value is rendered to %d bytes by %s", tt.name, len(tt.value), len(out), format)
+			}
+			if !bytes.Contains(out, []byte(`"@ref `)) {
+				t.Errorf("%s: shared value is not shown as reference", tt.name)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("%s: decode took %s by %s", tt.name, d, format)
+			}
+		}
+	}
+}
+
+func TestRenderDecodedLimit(t *testing.T) {
+	large := strings.Repeat("x", 1<<20)
+
+	list := make([]interface{}, 0, 32)
+	for i := 0; i < cap(list); i++ {
+		list = append(list, large)
+	}
+
+	if out, err := renderDecoded(list); err != errDecodedTooLarge {
+		t.Errorf("rendered %d bytes without error: %v", len(out), err)
+	}
+
+	// indent of deep nested value
+	var deep interface{} = large[:100]
+	for i := 0; i < maxDecodeDepth; i++ {
+		deep = []interface{}{deep, deep}
+	}
+
+	if out, err := renderDecoded(deep); err != errDecodedTooLarge {
+		t.Errorf("rendered %d bytes without error: %v", len(out), err)
+	}
+}
+
+func FuzzDecodeValue(f *testing.F) {
+	for _, tt := range decoderTests {
+		if strings.HasPrefix(tt.value, "!") {
+			f.Add([]byte(tt.value[1:]))
+		} else {
+			b, _ := hex.DecodeString(strings.Join(strings.Fields(tt.value), ""))
+			f.Add(b)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, value []byte) {
+		for _, name := range decoderNames() {
+			decodeValue(value, name)
+		}
+	})
+}
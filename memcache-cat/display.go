@@ -0,0 +1,101 @@
+package mccat
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// display modes of values
+const (
+	displayAuto    = "auto"
+	displayRaw     = "raw"
+	displayHex     = "hex"
+	displayEscaped = "escaped"
+)
+
+var displayModes = []string{displayAuto, displayRaw, displayHex, displayEscaped}
+
+// isDisplayMode check name is display mode
+func isDisplayMode(name string) bool {
+	for _, mode := range displayModes {
+		if name == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setDisplay change display mode of values
+func (c *Client) setDisplay(mode string) error {
+	mode = strings.ToLower(mode)
+	if mode == "" {
+		mode = displayAuto
+	}
+
+	if !isDisplayMode(mode) {
+		return fmt.Errorf("unknown display mode %s (%s)", mode, strings.Join(displayModes, ", "))
+	}
+
+	c.display = mode
+
+	return nil
+}
+
+// resolveDisplay return display mode for value. auto is hex when value is not valid UTF-8,
+// and escaped when value has control characters except tab and newlines (they break terminal)
+func resolveDisplay(value []byte, mode string) string {
+	if mode != displayAuto {
+		return mode
+	}
+
+	if !utf8.Valid(value) {
+		return displayHex
+	}
+
+	if hasControlChar(value) {
+		return displayEscaped
+	}
+
+	return displayRaw
+}
+
+// hasControlChar check value has C0 control characters (except \t, \n and \r) or DEL
+func hasControlChar(value []byte) bool {
+	for _, b := range value {
+		if (b < 0x20 && b != '\t' && b != '\n' && b != '\r') || b == 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// displayValue format value by display mode
+func displayValue(value []byte, mode string) []byte {
+	switch resolveDisplay(value, mode) {
+	case displayHex:
+		return hexDump(value)
+	case displayEscaped:
+		return []byte(strconv.Quote(string(value)))
+	}
+
+	return value
+}
+
+// hexDump format value like hexdump -C (offset, hex bytes and printable characters, and size at the end)
+func hexDump(value []byte) []byte {
+	return []byte(hex.Dump(value) + fmt.Sprintf("%08x\n", len(value)))
+}
+
+// valueText return value for text of table format. hex dump starts from next line of key
+func valueText(value []byte, mode string) string {
+	if resolveDisplay(value, mode) == displayHex {
+		return "\n" + strings.TrimSuffix(string(hexDump(value)), "\n")
+	}
+
+	return " " + string(displayValue(value, mode))
+}
@@ -0,0 +1,178 @@
+package mccat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// lineReader read lines from input and count them (for show line number of script)
+type lineReader struct {
+	r     *bufio.Reader
+	lines int
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// readLine read one line without CRLF. last line without LF is also returned
+func (l *lineReader) readLine() (string, error) {
+	line, err := l.r.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	l.lines++
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// commandValue return value of storage command.
+// inline value is taken as is except <<SENTINEL (following lines until SENTINEL line).
+// when value is not inline, it is read from file (--file option), editor (--editor option) or one line of input
+func (c *Client) commandValue(cmds *cmds) ([]byte, error) {
+	if cmds.ops.file != "" {
+		if cmds.hasValue {
+			return nil, fmt.Errorf("value is given by both --file and inline value")
+		}
+
+		return c.fileValue(cmds.ops.file)
+	}
+
+	if !cmds.hasValue {
+		if cmds.ops.editor {
+			return c.editorValue(nil, "")
+		}
+
+		value, err := c.readValue()
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(value), nil
+	}
+
+	value := string(cmds.value)
+	if len(value) > 2 && strings.HasPrefix(value, "<<") {
+		return c.readHeredoc(value[2:])
+	}
+
+	return cmds.value, nil
+}
+
+// fileValue read content of file as is (- is whole stdin)
+func (c *Client) fileValue(path string) ([]byte, error) {
+	if path == "-" {
+		data, err := ioutil.ReadAll(c.input.r)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read value from stdin: %s", err.Error())
+		}
+
+		return data, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read value from file: %s", err.Error())
+	}
+
+	return data, nil
+}
+
+// readValue show input prompt (only in interactive console) and read value
+func (c *Client) readValue() (string, error) {
+	if !c.batch {
+		fmt.Printf("input value> ")
+	}
+
+	value, err := c.input.readLine()
+	if err == io.EOF {
+		return "", fmt.Errorf("value is missing")
+	}
+
+	return value, err
+}
+
+// readHeredoc read lines until sentinel line. value does not include last line break
+func (c *Client) readHeredoc(sentinel string) ([]byte, error) {
+	var lines []string
+
+	if !c.batch {
+		fmt.Printf("input value (end with %s)>\n", sentinel)
+	}
+
+	for {
+		line, err := c.input.readLine()
+		if err == io.EOF {
+			return nil, fmt.Errorf("value is not terminated by %s", sentinel)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == sentinel {
+			break
+		}
+
+		lines = append(lines, line)
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// editorValue open $EDITOR (vi when it is not defined) with initial value and return edited value.
+// suffix is extension of temporary file for syntax highlight of editor (ex: .json)
+func (c *Client) editorValue(initial []byte, suffix string) ([]byte, error) {
+	f, err := ioutil.TempFile("", "mccat-*"+suffix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temporary file for editor: %s", err.Error())
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(initial); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot write temporary file for editor: %s", err.Error())
+	}
+	f.Close()
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// $EDITOR can have arguments (ex: code --wait)
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed: %s", editor, err.Error())
+	}
+
+	value, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("cannot read temporary file of editor: %s", err.Error())
+	}
+
+	// editors append line break at the end of file
+	if !bytes.HasSuffix(initial, []byte("\n")) {
+		value = bytes.TrimSuffix(value, []byte("\n"))
+	}
+
+	if len(value) == 0 {
+		return nil, fmt.Errorf("value is empty. canceled")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,412 @@
+package mccat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyInfo is metadata of key listed by lru_crawler metadump or stats cachedump.
+// cachedump of old servers only has Key, Expiration, Slab and Size (size of value)
+type KeyInfo struct {
+	Key string
+	// Expiration is unix time when key expires (-1 is never expire)
+	Expiration int64
+	// LastAccess is unix time of last access (0 is unknown)
+	LastAccess int64
+	CAS        uint64
+	// Fetched means key is fetched after stored
+	Fetched bool
+	// Slab is slab class ID of item
+	Slab int
+	// Size is size of item in memory (key, value and item header) by metadump,
+	// and size of value by cachedump (cachedump does not show item size)
+	Size int
+}
+
+// KeyFilter select keys listed by Keys. empty fields match all keys
+type KeyFilter struct {
+	// Namespace is part of key before Separator. keys in ExcludeNamespace are not matched
+	Namespace        string
+	ExcludeNamespace string
+	// Grep are words contained in key (all of them, or any of them when GrepAny is true).
+	// keys contain any of ExcludeGrep are not matched
+	Grep        []string
+	GrepAny     bool
+	ExcludeGrep []string
+	// Regex is regular expression matched with key
+	Regex *regexp.Regexp
+	// Glob is shell pattern matched with whole key (*, ? and [...] are supported)
+	Glob string
+	// MinSize and MaxSize are range of Size of KeyInfo (0 is unlimited)
+	MinSize int
+	MaxSize int
+	// ExpiresWithin match keys which expire within duration
+	ExpiresWithin time.Duration
+	// NoExpiry match keys which never expire
+	NoExpiry bool
+	// IdleLongerThan match keys which are not accessed longer than duration.
+	// keys without last access (cachedump of old servers) are not matched
+	IdleLongerThan time.Duration
+	// Slab is slab class ID of keys (0 is all slabs)
+	Slab int
+	// Separator is separator of namespace in key (default is KeySeparator of Config)
+	Separator string
+
+	// glob is Glob compiled by Compile (globSource is Glob when it is compiled)
+	glob       *regexp.Regexp
+	globSource string
+}
+
+// Compile check and compile Glob. Match compiles Glob for each key when filter is not compiled,
+// so call it before matching many keys (Keys compiles copy of filter)
+func (f *KeyFilter) Compile() error {
+	f.glob, f.globSource = nil, ""
+
+	if f.Glob == "" {
+		return nil
+	}
+
+	glob, err := globRegexp(f.Glob)
+	if err != nil {
+		return err
+	}
+	f.glob, f.globSource = glob, f.Glob
+
+	return nil
+}
+
+// Match check key matches with all conditions of filter.
+// filter is not changed, so it can be shared by goroutines (key is not matched when Glob is wrong)
+func (f *KeyFilter) Match(info *KeyInfo) bool {
+	separator := f.Separator
+	if separator == "" {
+		separator = ":"
+	}
+
+	ns := strings.SplitN(info.Key, separator, 2)[0]
+
+	// if namespace defined, compare with namespace
+	if f.Namespace != "" && ns != f.Namespace {
+		return false
+	}
+	// if exclude namespace defined, compare with namespace
+	if f.ExcludeNamespace != "" && ns == f.ExcludeNamespace {
+		return false
+	}
+	// if grep words defined, check about key contains words
+	if len(f.Grep) > 0 && !containsWords(info.Key, f.Grep, f.GrepAny) {
+		return false
+	}
+	// if exclude grep words defined, check about key contains one of words
+	if len(f.ExcludeGrep) > 0 && containsWords(info.Key, f.ExcludeGrep, true) {
+		return false
+	}
+	if f.Regex != nil && !f.Regex.MatchString(info.Key) {
+		return false
+	}
+	if f.Glob != "" {
+		glob := f.glob
+		if glob == nil || f.globSource != f.Glob {
+			var err error
+			if glob, err = globRegexp(f.Glob); err != nil {
+				return false
+			}
+		}
+
+		if !glob.MatchString(info.Key) {
+			return false
+		}
+	}
+
+	if f.Slab > 0 && info.Slab != f.Slab {
+		return false
+	}
+	if f.MinSize > 0 && info.Size < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && info.Size > f.MaxSize {
+		return false
+	}
+
+	return f.matchTime(info, time.Now())
+}
+
+// matchTime check expiration and last access of key
+func (f *KeyFilter) matchTime(info *KeyInfo, now time.Time) bool {
+	if f.NoExpiry && info.Expiration != -1 {
+		return false
+	}
+	if f.ExpiresWithin > 0 && (info.Expiration == -1 || time.Unix(info.Expiration, 0).After(now.Add(f.ExpiresWithin))) {
+		return false
+	}
+	if f.IdleLongerThan > 0 && (info.LastAccess == 0 || time.Unix(info.LastAccess, 0).After(now.Add(-f.IdleLongerThan))) {
+		return false
+	}
+
+	return true
+}
+
+// containsWords check key contains all words (or any of words)
+func containsWords(key string, words []string, anyWord bool) bool {
+	for _, w := range words {
+		if strings.Contains(key, w) == anyWord {
+			return anyWord
+		}
+	}
+
+	return !anyWord
+}
+
+// globRegexp convert shell pattern to regular expression matches whole key.
+// * and ? also match separator of namespace, unlike path.Match
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 >= len(pattern) {
+				return nil, fmt.Errorf("wrong glob pattern %s: trailing backslash", pattern)
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			// ] just after [ (or [!) is character of class
+			start := i + 1
+			if start < len(pattern) && pattern[start] == '!' {
+				start++
+			}
+			end := -1
+			if start < len(pattern) {
+				end = strings.IndexByte(pattern[start+1:], ']')
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("wrong glob pattern %s: [ is not closed", pattern)
+			}
+			end += start + 1
+
+			class := pattern[i+1 : end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			class = strings.NewReplacer("\\", "\\\\", "[", "\\[").Replace(class)
+			b.WriteString("[" + class + "]")
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	b.WriteString(")$")
+
+	return regexp.Compile(b.String())
+}
+
+// Keys call fn for each key matched with filter. keys are listed by lru_crawler metadump
+// (stats cachedump for old servers and binary protocol), and listing is stopped when fn returns error
+// or ctx is done. connection is reconnected when listing is canceled in the middle of dump.
+// fn must not call methods of c because dump is streamed on the connection of c until listing ends
+// (use another Client, or collect keys and use them after Keys returns)
+func (c *Client) Keys(ctx context.Context, filter KeyFilter, fn func(*KeyInfo) error) error {
+	if filter.Separator == "" {
+		filter.Separator = c.keySeparator()
+	}
+
+	if err := filter.Compile(); err != nil {
+		return err
+	}
+
+	// unblock reading dump when ctx is done
+	canceled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		c.deadlineMu.Lock()
+		c.canceled = true
+		c.Conn.SetReadDeadline(time.Now())
+		c.deadlineMu.Unlock()
+		close(canceled)
+	})
+
+	err := c.dumpKeys(filter.Slab, func(info *KeyInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !filter.Match(info) {
+			return nil
+		}
+
+		return fn(info)
+	})
+
+	if !stop() {
+		// clear deadline for next commands, and discard rest of dump when reading is interrupted
+		<-canceled
+		c.deadlineMu.Lock()
+		c.canceled = false
+		c.Conn.SetReadDeadline(time.Time{})
+		c.deadlineMu.Unlock()
+
+		if c.broken {
+			if err := c.Reconnect(); err != nil {
+				return fmt.Errorf("failed to reconnect after canceled: %s", err.Error())
+			}
+		}
+
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// errMetadumpUnsupported means server cannot dump keys by lru_crawler (old server or crawler is disabled)
+var errMetadumpUnsupported = errors.New("lru_crawler metadump is not supported")
+
+// dumpKeys call fn for each key in slab (all slabs when slab is 0). keys are listed by lru_crawler metadump,
+// and stats cachedump of each slab is used for old servers (up to about 2MB of keys per slab)
+func (c *Client) dumpKeys(slab int, fn func(*KeyInfo) error) error {
+	if c.proto.name() == protocolASCII {
+		err := c.metadump(slab, fn)
+		if err != errMetadumpUnsupported {
+			return err
+		}
+	}
+
+	return c.cachedump(slab, fn)
+}
+
+// metadump stream keys by lru_crawler metadump all (or slab class ID).
+// rest of dump is read even when fn returns error, for not to leave responses on connection
+func (c *Client) metadump(slab int, fn func(*KeyInfo) error) error {
+	classes := "all"
+	if slab > 0 {
+		classes = strconv.Itoa(slab)
+	}
+
+	if err := c.Write("lru_crawler metadump " + classes); err != nil {
+		return err
+	}
+
+	var fnErr error
+
+	for first := true; ; first = false {
+		// dump of large cache takes long time, so timeout is applied to each line
+		c.setDeadline()
+
+		buff, err := c.Read()
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		switch {
+		case buff == "END":
+			return fnErr
+		case strings.HasPrefix(buff, "key="):
+			if fnErr != nil {
+				continue
+			}
+
+			info, err := parseMetadump(buff)
+			if err != nil {
+				fnErr = err
+				continue
+			}
+
+			fnErr = fn(info)
+		case strings.HasPrefix(buff, "BUSY"):
+			return fmt.Errorf("lru_crawler of memcached server is busy: %s", buff)
+		case first && strings.Contains(buff, "ERROR"):
+			return errMetadumpUnsupported
+		default:
+			return fmt.Errorf("got unexpected response on lru_crawler metadump: %s", buff)
+		}
+	}
+}
+
+// parseMetadump parse line of metadump
+// key=<url encoded key> exp=<unix time> la=<unix time> cas=<cas> fetch=<yes|no> cls=<slab> size=<size>
+func parseMetadump(line string) (*KeyInfo, error) {
+	info := &KeyInfo{Expiration: -1}
+
+	for _, kv := range strings.Fields(line) {
+		f := strings.SplitN(kv, "=", 2)
+		if len(f) != 2 {
+			continue
+		}
+
+		var err error
+
+		switch f[0] {
+		case "key":
+			info.Key, err = url.PathUnescape(f[1])
+		case "exp":
+			info.Expiration, err = strconv.ParseInt(f[1], 10, 64)
+		case "la":
+			info.LastAccess, err = strconv.ParseInt(f[1], 10, 64)
+		case "cas":
+			info.CAS, err = strconv.ParseUint(f[1], 10, 64)
+		case "fetch":
+			info.Fetched = f[1] == "yes"
+		case "cls":
+			info.Slab, err = strconv.Atoi(f[1])
+		case "size":
+			info.Size, err = strconv.Atoi(f[1])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("got malformed %s in metadump [%s]: %s", f[0], line, err.Error())
+		}
+	}
+
+	if info.Key == "" {
+		return nil, fmt.Errorf("got metadump without key: %s", line)
+	}
+
+	return info, nil
+}
+
+// cachedump list keys by stats cachedump of each slab
+// ITEM <key> [<size> b; <exp> s]
+func (c *Client) cachedump(slab int, fn func(*KeyInfo) error) error {
+	slabIDs := []int{slab}
+
+	if slab == 0 {
+		var err error
+		if slabIDs, _, err = c.getSlabDataAndKeyCount(); err != nil {
+			return fmt.Errorf("cannot get slab data from memcached server: %s", err.Error())
+		}
+	}
+
+	for _, slab := range slabIDs {
+		items, err := c.proto.stats(fmt.Sprintf("cachedump %d 0", slab))
+		if err != nil {
+			return err
+		}
+
+		for _, it := range items {
+			info := &KeyInfo{Key: it.name, Slab: slab}
+
+			// expiration of cachedump is 0 for never expire items
+			if _, err := fmt.Sscanf(it.value, "[%d b; %d s]", &info.Size, &info.Expiration); err == nil && info.Expiration == 0 {
+				info.Expiration = -1
+			}
+
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+package mccat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// lz4 frame format (https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md)
+// and block format (https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md)
+
+const (
+	lz4FrameMagic    = 0x184D2204
+	lz4MinMatch      = 4
+	lz4MaxBlockSize  = 4 << 20
+	lz4LastLiterals  = 5
+	lz4MFLimit       = 12
+	lz4HashLog       = 16
+	lz4MaxOffset     = 65535
+	lz4BlockSize64KB = 64 << 10
+	// lz4MaxRatio is limit of expansion ratio (each 255 of length bytes makes 255 bytes of match)
+	lz4MaxRatio = 255
+)
+
+var errLZ4Corrupted = errors.New("lz4: corrupted data")
+
+// lz4DecodeBlock decode lz4 block and append result to dst (up to limit bytes of dst).
+// dst can have previous blocks data for dependent blocks of frame
+func lz4DecodeBlock(dst []byte, src []byte, limit int) ([]byte, error) {
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		// literals
+		literalLen := int(token >> 4)
+		if literalLen == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errLZ4Corrupted
+				}
+				literalLen += int(src[i])
+				i++
+				if src[i-1] != 255 {
+					break
+				}
+			}
+		}
+		if i+literalLen > len(src) || len(dst)+literalLen > limit {
+			return nil, errLZ4Corrupted
+		}
+		dst = append(dst, src[i:i+literalLen]...)
+		i += literalLen
+
+		// last sequence has only literals
+		if i == len(src) {
+			break
+		}
+
+		// match
+		if i+2 > len(src) {
+			return nil, errLZ4Corrupted
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errLZ4Corrupted
+		}
+
+		matchLen := int(token & 0x0f)
+		if matchLen == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errLZ4Corrupted
+				}
+				matchLen += int(src[i])
+				i++
+				if src[i-1] != 255 {
+					break
+				}
+			}
+		}
+		matchLen += lz4MinMatch
+		if len(dst)+matchLen > limit {
+			return nil, errLZ4Corrupted
+		}
+
+		// match can overlap with itself, so copy up to offset bytes at once
+		for pos := len(dst) - offset; matchLen > 0; {
+			n := min(matchLen, len(dst)-pos)
+			dst = append(dst, dst[pos:pos+n]...)
+			pos += n
+			matchLen -= n
+		}
+	}
+
+	return dst, nil
+}
+
+// lz4Decode decode lz4 frame, or block with 4 bytes little endian size prefix (lz4.block of python),
+// or raw block
+func lz4Decode(src []byte) ([]byte, error) {
+	if len(src) >= 4 && binary.LittleEndian.Uint32(src) == lz4FrameMagic {
+		return lz4DecodeFrame(src)
+	}
+
+	// size prefixed block. size is trusted only when block can be decoded to it
+	if len(src) > 4 {
+		size := int(binary.LittleEndian.Uint32(src))
+		if size <= (len(src)-4)*lz4MaxRatio {
+			if out, err := lz4DecodeBlock(make([]byte, 0, size), src[4:], size); err == nil && len(out) == size {
+				return out, nil
+			}
+		}
+	}
+
+	return lz4DecodeBlock(nil, src, len(src)*lz4MaxRatio)
+}
+
+func lz4DecodeFrame(src []byte) ([]byte, error) {
+	if len(src) < 7 {
+		return nil, errLZ4Corrupted
+	}
+
+	flg := src[4]
+	if flg>>6 != 1 {
+		return nil, fmt.Errorf("lz4: unsupported frame version %d", flg>>6)
+	}
+
+	blockChecksum := flg&0x10 != 0
+	contentSize := flg&0x08 != 0
+	contentChecksum := flg&0x04 != 0
+	dictID := flg&0x01 != 0
+
+	i := 6
+	if contentSize {
+		i += 8
+	}
+	if dictID {
+		i += 4
+	}
+
+	// header checksum
+	if i >= len(src) {
+		return nil, errLZ4Corrupted
+	}
+	if byte(xxh32(src[4:i], 0)>>8) != src[i] {
+		return nil, fmt.Errorf("lz4: wrong frame header checksum")
+	}
+	i++
+
+	var out []byte
+
+	for {
+		if i+4 > len(src) {
+			return nil, errLZ4Corrupted
+		}
+		size := binary.LittleEndian.Uint32(src[i:])
+		i += 4
+
+		// end mark
+		if size == 0 {
+			break
+		}
+
+		uncompressed := size&0x80000000 != 0
+		size &= 0x7fffffff
+		if i+int(size) > len(src) {
+			return nil, errLZ4Corrupted
+		}
+
+		block := src[i : i+int(size)]
+		i += int(size)
+
+		if uncompressed {
+			out = append(out, block...)
+		} else {
+			var err error
+			if out, err = lz4DecodeBlock(out, block, len(out)+lz4MaxBlockSize); err != nil {
+				return nil, err
+			}
+		}
+
+		if blockChecksum {
+			i += 4
+		}
+	}
+
+	if contentChecksum {
+		if i+4 > len(src) {
+			return nil, errLZ4Corrupted
+		}
+		if binary.LittleEndian.Uint32(src[i:]) != xxh32(out, 0) {
+			return nil, fmt.Errorf("lz4: wrong content checksum")
+		}
+	}
+
+	return out, nil
+}
+
+// lz4EncodeBlock compress src to lz4 block by greedy matching
+func lz4EncodeBlock(src []byte) []byte {
+	var dst []byte
+	var table [1 << lz4HashLog]int
+
+	hash := func(i int) uint32 {
+		return (binary.LittleEndian.Uint32(src[i:]) * 2654435761) >> (32 - lz4HashLog)
+	}
+
+	anchor := 0
+
+	for i := 0; i+lz4MFLimit <= len(src); {
+		h := hash(i)
+		candidate := table[h] - 1
+		table[h] = i + 1
+
+		if candidate < 0 || i-candidate > lz4MaxOffset ||
+			binary.LittleEndian.Uint32(src[candidate:]) != binary.LittleEndian.Uint32(src[i:]) {
+			i++
+			continue
+		}
+
+		// extend match (last 5 bytes must be literals)
+		matchLen := lz4MinMatch
+		for i+matchLen < len(src)-lz4LastLiterals && src[candidate+matchLen] == src[i+matchLen] {
+			matchLen++
+		}
+
+		dst = lz4AppendSequence(dst, src[anchor:i], i-candidate, matchLen)
+
+		i += matchLen
+		anchor = i
+	}
+
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence append literals and match (last sequence has no match when matchLen is 0)
+func lz4AppendSequence(dst []byte, literals []byte, offset int, matchLen int) []byte {
+	token := byte(0)
+	ml := matchLen - lz4MinMatch
+
+	if len(literals) >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(len(literals)) << 4
+	}
+	if matchLen > 0 {
+		if ml >= 15 {
+			token |= 15
+		} else {
+			token |= byte(ml)
+		}
+	}
+
+	dst = append(dst, token)
+	dst = lz4AppendLength(dst, len(literals))
+	dst = append(dst, literals...)
+
+	if matchLen == 0 {
+		return dst
+	}
+
+	dst = append(dst, byte(offset), byte(offset>>8))
+
+	return lz4AppendLength(dst, ml)
+}
+
+// lz4AppendLength append rest of length over 15 (255 continues)
+func lz4AppendLength(dst []byte, length int) []byte {
+	if length < 15 {
+		return dst
+	}
+
+	for length -= 15; length >= 255; length -= 255 {
+		dst = append(dst, 255)
+	}
+
+	return append(dst, byte(length))
+}
+
+// lz4Encode compress src to lz4 frame with independent 64KB blocks
+func lz4Encode(src []byte) []byte {
+	// version 01, independent blocks, content checksum
+	header := []byte{0x64, 0x40}
+
+	dst := make([]byte, 4, len(src)/2+32)
+	binary.LittleEndian.PutUint32(dst, lz4FrameMagic)
+	dst = append(dst, header...)
+	dst = append(dst, byte(xxh32(header, 0)>>8))
+
+	for i := 0; i < len(src); i += lz4BlockSize64KB {
+		end := i + lz4BlockSize64KB
+		if end > len(src) {
+			end = len(src)
+		}
+
+		block := lz4EncodeBlock(src[i:end])
+		size := uint32(len(block))
+
+		// store as is when data cannot be compressed
+		if len(block) >= end-i {
+			block = src[i:end]
+			size = uint32(len(block)) | 0x80000000
+		}
+
+		dst = binary.LittleEndian.AppendUint32(dst, size)
+		dst = append(dst, block...)
+	}
+
+	dst = binary.LittleEndian.AppendUint32(dst, 0)
+
+	return binary.LittleEndian.AppendUint32(dst, xxh32(src, 0))
+}
+
+const (
+	xxhPrime32x1 uint32 = 2654435761
+	xxhPrime32x2 uint32 = 2246822519
+	xxhPrime32x3 uint32 = 3266489917
+	xxhPrime32x4 uint32 = 668265263
+	xxhPrime32x5 uint32 = 374761393
+)
+
+// xxh32 is xxHash32 which is used for checksum of lz4 frame
+func xxh32(data []byte, seed uint32) uint32 {
+	var h uint32
+
+	round := func(acc, input uint32) uint32 {
+		return bits.RotateLeft32(acc+input*xxhPrime32x2, 13) * xxhPrime32x1
+	}
+
+	i := 0
+	if len(data) >= 16 {
+		v1 := seed + xxhPrime32x1 + xxhPrime32x2
+		v2 := seed + xxhPrime32x2
+		v3 := seed
+		v4 := seed - xxhPrime32x1
+
+		for ; i+16 <= len(data); i += 16 {
+			v1 = round(v1, binary.LittleEndian.Uint32(data[i:]))
+			v2 = round(v2, binary.LittleEndian.Uint32(data[i+4:]))
+			v3 = round(v3, binary.LittleEndian.Uint32(data[i+8:]))
+			v4 = round(v4, binary.LittleEndian.Uint32(data[i+12:]))
+		}
+
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxhPrime32x5
+	}
+
+	h += uint32(len(data))
+
+	for ; i+4 <= len(data); i += 4 {
+		h += binary.LittleEndian.Uint32(data[i:]) * xxhPrime32x3
+		h = bits.RotateLeft32(h, 17) * xxhPrime32x4
+	}
+	for ; i < len(data); i++ {
+		h += uint32(data[i]) * xxhPrime32x5
+		h = bits.RotateLeft32(h, 11) * xxhPrime32x1
+	}
+
+	h ^= h >> 15
+	h *= xxhPrime32x2
+	h ^= h >> 13
+	h *= xxhPrime32x3
+	h ^= h >> 16
+
+	return h
+}
@@ -0,0 +1,136 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestXXH32(t *testing.T) {
+	tests := []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0x02cc5d05},
+		{"a", 0, 0x550d7456},
+		{"abc", 0, 0x32d153ff},
+		{"Nobody inspects the spammish repetition", 0, 0xe2293b2f},
+		{"", 1, 0x0b2cb792},
+	}
+
+	for _, tt := range tests {
+		if got := xxh32([]byte(tt.data), tt.seed); got != tt.want {
+			t.Errorf("xxh32(%q, %d) = %08x, want %08x", tt.data, tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestLZ4Decode(t *testing.T) {
+	repeated := strings.Repeat("abcd", 20) + "xyz"
+
+	// frames are made by lz4 command line tool 1.9.4
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty frame", "04224d186440a700000000055dcc02", ""},
+		{"uncompressed block frame", "04224d186440a70300008061626300000000ff53d132", "abc"},
+		{"compressed frame", "04224d186440a70e0000004f6162636404003750636478797a00000000895559dc", repeated},
+		{"dependent blocks frame without checksum", "04224d186040820e0000004f6162636404003750636478797a00000000", repeated},
+		{"size prefixed block", "530000004f6162636404003750636478797a", repeated},
+		{"raw block", "4f6162636404003750636478797a", repeated},
+	}
+
+	for _, tt := range tests {
+		got, err := lz4Decode(mustHex(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLZ4DecodeCorrupted(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"truncated frame header", "04224d1864"},
+		{"wrong header checksum", "04224d186440a800000000055dcc02"},
+		{"wrong content checksum", "04224d186440a70300008061626300000000ff53d133"},
+		{"truncated block", "04224d186440a70e0000004f61626364"},
+		{"offset before start", "4f616263641000"},
+		{"zero offset", "4f616263640000"},
+		{"literals over end", "f0ff"},
+	}
+
+	for _, tt := range tests {
+		if got, err := lz4Decode(mustHex(t, tt.src)); err == nil {
+			t.Errorf("%s: got %q without error", tt.name, got)
+		}
+	}
+}
+
+func TestLZ4DecodeLargeSizePrefix(t *testing.T) {
+	// size prefix says 256MB, but 8 bytes of block cannot be decoded to it
+	src := mustHex(t, "000000104f61626364040000")
+
+	start := time.Now()
+	if out, err := lz4Decode(src); err == nil && len(out) > len(src)*lz4MaxRatio {
+		t.Errorf("decoded %d bytes from %d bytes", len(out), len(src))
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("decode took %s", d)
+	}
+}
+
+func TestLZ4RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	random := make([]byte, 100<<10)
+	r.Read(random)
+
+	inputs := [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte("hello, world"),
+		bytes.Repeat([]byte("memcached "), 20000),
+		random,
+		append(bytes.Repeat([]byte{0}, 70<<10), random[:1000]...),
+	}
+
+	for _, in := range inputs {
+		frame, err := lz4Decode(lz4Encode(in))
+		if err != nil || !bytes.Equal(frame, in) {
+			t.Errorf("frame round trip of %d bytes failed: %v", len(in), err)
+		}
+
+		if len(in) > lz4BlockSize64KB {
+			continue
+		}
+
+		block, err := lz4DecodeBlock(nil, lz4EncodeBlock(in), len(in))
+		if err != nil || !bytes.Equal(block, in) {
+			t.Errorf("block round trip of %d bytes failed: %v", len(in), err)
+		}
+	}
+}
@@ -2,17 +2,31 @@ package mccat
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	defaultTTL = 3600
+	// getMultiChunkSize is number of keys sent by one multi get request
+	getMultiChunkSize = 100
+)
+
+var (
+	// ErrCacheMiss means that key does not exist in memcached server
+	ErrCacheMiss = errors.New("got error! (cache missed)")
+	// ErrCASConflict means that item was modified after it fetched by gets
+	ErrCASConflict = errors.New("cas conflict: item modified by other client")
 )
 
 type cmds struct {
@@ -20,48 +34,102 @@ type cmds struct {
 	ops         options
 	maxArgCount int
 	getall      bool
+	retrieve    bool
+	store       bool
+	meta        bool
+	// valueIndex is position of inline value in argv (0 means command does not take value)
+	valueIndex int
+	value      []byte
+	hasValue   bool
 }
 
 type options struct {
-	namespace  string
-	vnamespace string
-	grep       string
-	vgrep      string
-	keyOnly    bool
-	countOnly  bool
+	filter    KeyFilter
+	keyOnly   bool
+	countOnly bool
+	flags     uint32
+	editor    bool
+	file      string
+	out       string
+	outDir    string
+	compress  string
+	decoder   string
+	display   string
+}
+
+// Config is optional settings of memcache client
+type Config struct {
+	// Protocol is wire protocol to communicate with server (ascii or binary, default is ascii)
+	Protocol string
+	// Username and Password are used for authentication when Username is not empty
+	// (SASL PLAIN on binary protocol, authfile token on ascii protocol)
+	Username string
+	Password string
+	// TLS settings are used when url starts with tls://
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+	// Timeout is limit of connecting and each request to server (0 means no limit)
+	Timeout time.Duration
+	// DefaultTTL is used when ttl is omitted or wrong (0 means 3600 sec)
+	DefaultTTL int
+	// ReadOnly reject commands which modify items
+	ReadOnly bool
+	// Output is format of command results (table, raw, json, jsonl, csv or tsv. default is table)
+	Output string
+	// KeySeparator is separator between namespace and rest of key (default is ":")
+	KeySeparator string
+	// CompressionFlags is comma separated flag bits of compressed value (pymemcache, php, spymemcached
+	// or bit:codec like 16:zstd). compressed values are also detected by magic bytes
+	CompressionFlags string
+	// NoDecompress show compressed values as is
+	NoDecompress bool
+	// Decode is serialization format for decode values (auto, none, json, php, igbinary, pickle,
+	// java, cbor or msgpack. default is none)
+	Decode string
+	// Display is display mode of values (auto, raw, hex or escaped. default is auto which shows
+	// hex dump of value when it is not valid UTF-8, and quoted string when it has control characters)
+	Display string
 }
 
 // Client is a memcache client.
 type Client struct {
 	Conn        net.Conn
 	buff        *bufio.ReadWriter
+	proto       protocol
+	config      Config
 	historyFile *os.File
 	historyRW   *bufio.ReadWriter
 	url         string
 	cmdHistory  []string
+	broken      bool
+	batch       bool
+	input       *lineReader
+	out         formatter
+	output      string
+	// display is display mode of values (auto, raw, hex or escaped)
+	display string
+	// compressionRules are parsed Config.CompressionFlags
+	compressionRules []flagRule
+	// canceled is set while Keys is canceled, and deadlineMu guards it and deadline of Conn
+	deadlineMu sync.Mutex
+	canceled   bool
 }
 
 // Item is struct of stored data
 type Item struct {
 	Key   string
-	Value string
-}
-
-func usage() {
-	fmt.Println("Command list")
-	fmt.Println("> get key [key2] [key3] ...                                             : Get data from server")
-	fmt.Println("> set key ttl                                                           : Set data (overwrite when exist)")
-	fmt.Println("> add key ttl                                                           : Add new data (error when key exist)")
-	fmt.Println("> append key ttl                                                        : Append data from exist data")
-	fmt.Println("> prepend key ttl                                                       : Prepend data from exist data")
-	fmt.Println("> replace key ttl                                                       : Replace data from exist data")
-	fmt.Println("> incr[increase] key number                                             : Increase numeric value")
-	fmt.Println("> decr[decrease] key number                                             : Decrease numeric value")
-	fmt.Println("> del[delete|rm|remove] key [key2] [key3] ...                           : Remove key item from server")
-	fmt.Println("> key_counts                                                            : Get key counts")
-	fmt.Println("> get_all [--name namespace] [--grep grep_words] --verbose              : Get \"almost\" all items from server (can grep by namespace or key words)")
-	fmt.Println("> flush_all                                                             : Get key counts")
-	fmt.Println("> help                                                                  : Show usage")
+	Value []byte
+	Flags uint32
+	TTL   int
+	CAS   uint64
+	Size  int
+	// Compression is codec of compressed value when Value is decompressed for display
+	Compression string
+	// Serialization is format of serialized value when Value is decoded to JSON for display
+	Serialization string
 }
 
 func getServerAddr(url string) string {
@@ -75,15 +143,18 @@ func getServerAddr(url string) string {
 	if strings.HasPrefix(url, "tcp://") {
 		url = strings.TrimPrefix(url, "tcp://")
 	}
+	if strings.HasPrefix(url, "tls://") {
+		url = strings.TrimPrefix(url, "tls://")
+	}
 	addr := strings.SplitN(url, ":", 2)
 	if len(addr) != 2 {
-		fmt.Println("connect to default port(11211)")
+		os.Stderr.WriteString("connect to default port(11211)\n")
 
 		port = 11211
 	} else {
 		port, err = strconv.Atoi(addr[1])
 		if err != nil {
-			fmt.Println("connect to default port(11211)")
+			os.Stderr.WriteString("connect to default port(11211)\n")
 
 			port = 11211
 		}
@@ -92,19 +163,20 @@ func getServerAddr(url string) string {
 	return fmt.Sprintf("%s:%d", addr[0], port)
 }
 
-func createConn(url string) (net.Conn, error) {
+func createConn(url string, config Config) (net.Conn, error) {
 	var nc net.Conn
 	var err error
 
+	useTLS := strings.HasPrefix(url, "tls://")
 	url = getServerAddr(url)
 
 	if strings.HasSuffix(url, ".sock") {
-		nc, err = net.Dial("unix", url)
+		nc, err = net.DialTimeout("unix", url, config.Timeout)
 		if err != nil {
 			return nil, fmt.Errorf("cannot connect to memcached socket: %s", err.Error())
 		}
 	} else {
-		nc, err = net.Dial("tcp", url)
+		nc, err = net.DialTimeout("tcp", url, config.Timeout)
 		if err != nil {
 			return nil, fmt.Errorf("cannot connect to memcached server: %s", err.Error())
 		}
@@ -120,15 +192,106 @@ func createConn(url string) (net.Conn, error) {
 		}
 	}
 
+	if useTLS {
+		tlsConfig, err := createTLSConfig(url, config)
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+
+		if config.Timeout > 0 {
+			nc.SetDeadline(time.Now().Add(config.Timeout))
+		}
+
+		tc := tls.Client(nc, tlsConfig)
+		if err := tc.Handshake(); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("failed on TLS handshake with memcached server: %s", err.Error())
+		}
+
+		nc.SetDeadline(time.Time{})
+
+		nc = tc
+	}
+
 	return nc, nil
 }
 
+func createTLSConfig(addr string, config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.TLSServerName,
+		InsecureSkipVerify: config.TLSInsecureSkipVerify,
+	}
+
+	// use host name of address for SNI when server name is not defined
+	if tlsConfig.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			tlsConfig.ServerName = host
+		}
+	}
+
+	if config.TLSCAFile != "" {
+		ca, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA file [%s]: %s", config.TLSCAFile, err.Error())
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("cannot find any PEM certificate in CA file [%s]", config.TLSCAFile)
+		}
+	}
+
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %s", err.Error())
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// TLSConnectionState return TLS version and cipher suite name of connection.
+// ok is false when connection is not TLS
+func (c *Client) TLSConnectionState() (version string, cipher string, ok bool) {
+	tc, ok := c.Conn.(*tls.Conn)
+	if !ok {
+		return "", "", false
+	}
+
+	state := tc.ConnectionState()
+
+	switch state.Version {
+	case tls.VersionTLS10:
+		version = "TLS 1.0"
+	case tls.VersionTLS11:
+		version = "TLS 1.1"
+	case tls.VersionTLS12:
+		version = "TLS 1.2"
+	case tls.VersionTLS13:
+		version = "TLS 1.3"
+	default:
+		version = fmt.Sprintf("unknown (0x%04x)", state.Version)
+	}
+
+	return version, tls.CipherSuiteName(state.CipherSuite), true
+}
+
 // New make connection to provided address:port
 // and returns a memcache client.
 func New(url string, cmdHistoryFilePath string) (*Client, error) {
+	return NewWithConfig(url, cmdHistoryFilePath, Config{})
+}
+
+// NewWithConfig make connection to provided address:port with config
+// and returns a memcache client.
+func NewWithConfig(url string, cmdHistoryFilePath string, config Config) (*Client, error) {
 	var historyFile *os.File
 
-	nc, err := createConn(url)
+	nc, err := createConn(url, config)
 	if err != nil {
 		return nil, err
 	}
@@ -146,10 +309,46 @@ func New(url string, cmdHistoryFilePath string) (*Client, error) {
 	c := &Client{
 		Conn:        nc,
 		url:         url,
+		config:      config,
 		historyFile: historyFile,
 		historyRW:   nil,
 		cmdHistory:  nil,
 		buff:        bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc)),
+		input:       newLineReader(os.Stdin),
+	}
+
+	c.proto, err = newProtocol(config.Protocol, c)
+	if err != nil {
+		c.Close(true)
+		return nil, err
+	}
+
+	c.compressionRules, err = parseCompressionFlags(config.CompressionFlags)
+	if err != nil {
+		c.Close(true)
+		return nil, err
+	}
+
+	if config.Decode != "" && !isDecoderName(config.Decode) {
+		c.Close(true)
+		return nil, fmt.Errorf("unknown decoder %s (use %s)", config.Decode, strings.Join(decoderNames(), ", "))
+	}
+
+	if err := c.setOutput(config.Output); err != nil {
+		c.Close(true)
+		return nil, err
+	}
+
+	if err := c.setDisplay(config.Display); err != nil {
+		c.Close(true)
+		return nil, err
+	}
+
+	if config.Username != "" {
+		if err := c.proto.authenticate(config.Username, config.Password); err != nil {
+			c.Close(true)
+			return nil, err
+		}
 	}
 
 	if c.historyFile != nil {
@@ -176,6 +375,31 @@ func New(url string, cmdHistoryFilePath string) (*Client, error) {
 	return c, nil
 }
 
+// Reconnect close current connection and make new connection with same settings.
+// authentication is performed again when credentials are configured
+func (c *Client) Reconnect() error {
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
+
+	nc, err := createConn(c.url, c.config)
+	if err != nil {
+		return err
+	}
+
+	c.Conn = nc
+	c.buff = bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))
+	c.broken = false
+
+	if c.config.Username != "" {
+		if err := c.proto.authenticate(c.config.Username, c.config.Password); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Start function is start mccat console
 func (c *Client) Start() error {
 	for {
@@ -195,6 +419,18 @@ func (c *Client) Start() error {
 				if err := c.Run(cmds); err != nil {
 					fmt.Printf("%s\n", err.Error())
 				}
+
+				// result of each command is separate document in console
+				c.out.close()
+			}
+		}
+
+		// reconnect when connection is lost while executing command
+		if c.broken {
+			fmt.Printf("connection to %s is lost. reconnecting...\n", c.url)
+
+			if err := c.Reconnect(); err != nil {
+				fmt.Printf("failed to reconnect: %s\n", err.Error())
 			}
 		}
 
@@ -217,33 +453,110 @@ func (c *Client) Start() error {
 	return nil
 }
 
-func calcTTL(ttl string) int {
+// defaultTTL return ttl of config, or 3600 sec when it is not configured
+func (c *Client) defaultTTL() int {
+	if c.config.DefaultTTL > 0 {
+		return c.config.DefaultTTL
+	}
+
+	return defaultTTL
+}
+
+// keySeparator return separator of namespace in key
+func (c *Client) keySeparator() string {
+	if c.config.KeySeparator != "" {
+		return c.config.KeySeparator
+	}
+
+	return ":"
+}
+
+func (c *Client) calcTTL(ttl string) int {
 	if ttl == "" {
-		return defaultTTL
+		return c.defaultTTL()
 	}
 
 	t, err := strconv.Atoi(ttl)
 	if err != nil {
-		os.Stderr.WriteString(fmt.Sprintf("ttl is wrong. use default ttl (%d).\n", defaultTTL))
-		return defaultTTL
+		os.Stderr.WriteString(fmt.Sprintf("ttl is wrong. use default ttl (%d).\n", c.defaultTTL()))
+		return c.defaultTTL()
 	}
 
 	return t
 }
 
-func readValueInput() (string, error) {
-	r := bufio.NewReader(os.Stdin)
+// Execute parse and run single command line without interactive console.
+// values of retrieval commands are written as raw bytes for pipe them to other commands
+// unless output format is configured
+func (c *Client) Execute(cmd string) error {
+	defer c.startBatch()()
 
-	buff, err := r.ReadString('\n')
+	cmds, err := parseCmd(cmd)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	// help command
+	if cmds == nil {
+		return nil
 	}
 
-	return strings.TrimRight(buff, "\r\n"), nil
+	return c.Run(cmds)
+}
+
+// ExecuteArgs run command which is already split into arguments (ex: arguments of shell)
+// same as Execute
+func (c *Client) ExecuteArgs(args []string) error {
+	defer c.startBatch()()
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	cmds, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+
+	// help command
+	if cmds == nil {
+		return nil
+	}
+
+	return c.Run(cmds)
+}
+
+// startBatch switch to batch mode with raw output (unless output format is configured or changed),
+// and return function which restores previous mode for interactive console
+func (c *Client) startBatch() func() {
+	batch, out, output := c.batch, c.out, c.output
+
+	var raw formatter
+	if c.config.Output == "" && c.output == outputTable {
+		raw = &rawFormatter{w: os.Stdout}
+		c.out, c.output = raw, outputRaw
+	}
+
+	c.batch = true
+
+	return func() {
+		c.batch = batch
+
+		// output format changed by command is kept
+		if raw != nil && c.out == raw {
+			c.out, c.output = out, output
+		}
+	}
 }
 
 // Run execute command line
 func (c *Client) Run(cmds *cmds) error {
+	if c.config.ReadOnly && isWriteCommand(cmds.argv[0]) {
+		return fmt.Errorf("%s is not allowed: connected as read-only", cmds.argv[0])
+	}
+
+	defer c.out.flush()
+
 	switch cmds.argv[0] {
 	case "keycounts":
 		err := c.GetAll(cmds.ops)
@@ -252,18 +565,17 @@ func (c *Client) Run(cmds *cmds) error {
 		}
 
 		break
-	case "get":
+	case "get", "gets":
 		if len(cmds.argv) < 2 {
 			return fmt.Errorf("key must needed")
 		}
 
-		for i := 1; i < len(cmds.argv); i++ {
-			item, err := c.Get(cmds.argv[i])
-			if err != nil {
-				fmt.Printf("%s : %s\n", cmds.argv[i], err.Error())
-			} else {
-				fmt.Printf("%s : %s\n", item.Key, item.Value)
-			}
+		if cmds.ops.out != "" {
+			return c.saveItem(cmds.argv[0], cmds.argv[1:], 0, cmds.ops.out)
+		}
+
+		if err := c.printItems(cmds.argv[0], cmds.argv[1:], 0, cmds.ops); err != nil {
+			return err
 		}
 
 		break
@@ -282,23 +594,189 @@ func (c *Client) Run(cmds *cmds) error {
 		}
 
 		if len(cmds.argv) < 3 {
-			ttl = defaultTTL
+			ttl = c.defaultTTL()
 		} else {
-			ttl = calcTTL(cmds.argv[2])
+			ttl = c.calcTTL(cmds.argv[2])
 		}
 
-		fmt.Printf("input value> ")
-
-		value, err := readValueInput()
+		value, err := c.commandValue(cmds)
 		if err != nil {
 			return err
 		}
 
+		if cmds.ops.compress != "" {
+			value, cmds.ops.flags, err = c.compress(cmds.ops.compress, value, cmds.ops.flags)
+			if err != nil {
+				return err
+			}
+		}
+
 		if err := c.Store(cmds, ttl, value); err != nil {
 			return err
 		}
 
-		fmt.Printf("key %s %s complate\n", cmds.argv[1], cmds.argv[0])
+		c.printResult(fmt.Sprintf("key %s %s complate", cmds.argv[1], cmds.argv[0]),
+			field{"command", cmds.argv[0]}, field{"key", cmds.argv[1]}, field{"status", "stored"})
+
+		break
+	case "gat", "gats":
+		if len(cmds.argv) < 2 {
+			return fmt.Errorf("ttl must needed")
+		}
+
+		if len(cmds.argv) < 3 {
+			return fmt.Errorf("key must needed")
+		}
+
+		if cmds.ops.out != "" {
+			return c.saveItem(cmds.argv[0], cmds.argv[2:], c.calcTTL(cmds.argv[1]), cmds.ops.out)
+		}
+
+		if err := c.printItems(cmds.argv[0], cmds.argv[2:], c.calcTTL(cmds.argv[1]), cmds.ops); err != nil {
+			return err
+		}
+
+		break
+	case "touch":
+		if len(cmds.argv) < 2 {
+			return fmt.Errorf("key must needed")
+		}
+
+		if len(cmds.argv) < 3 {
+			return fmt.Errorf("ttl must needed")
+		}
+
+		ttl := c.calcTTL(cmds.argv[2])
+
+		if err := c.Touch(cmds.argv[1], ttl); err != nil {
+			return fmt.Errorf("failed to touch key %s: %s", cmds.argv[1], err.Error())
+		}
+
+		c.printResult(fmt.Sprintf("key %s ttl updated to %d", cmds.argv[1], ttl),
+			field{"command", "touch"}, field{"key", cmds.argv[1]}, field{"ttl", ttl})
+
+		break
+	case "cas":
+		if len(cmds.argv) < 4 {
+			return fmt.Errorf("key, ttl and cas unique must needed")
+		}
+
+		casID, err := strconv.ParseUint(cmds.argv[3], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cas unique must be numeric: %s", cmds.argv[3])
+		}
+
+		value, err := c.commandValue(cmds)
+		if err != nil {
+			return err
+		}
+
+		if cmds.ops.compress != "" {
+			value, cmds.ops.flags, err = c.compress(cmds.ops.compress, value, cmds.ops.flags)
+			if err != nil {
+				return err
+			}
+		}
+
+		item := &Item{
+			Key:   cmds.argv[1],
+			Value: value,
+			Flags: cmds.ops.flags,
+			TTL:   c.calcTTL(cmds.argv[2]),
+			CAS:   casID,
+		}
+
+		if err := c.CompareAndSwap(item); err != nil {
+			return fmt.Errorf("failed to cas key %s: %s", item.Key, err.Error())
+		}
+
+		c.printResult(fmt.Sprintf("key %s cas complate", item.Key),
+			field{"command", "cas"}, field{"key", item.Key}, field{"status", "stored"})
+
+		break
+	case "edit":
+		if len(cmds.argv) < 2 {
+			return fmt.Errorf("key must needed")
+		}
+
+		// keep remaining ttl when ttl is omitted
+		ttl := -1
+		if len(cmds.argv) > 2 {
+			ttl = c.calcTTL(cmds.argv[2])
+		}
+
+		if err := c.Edit(cmds.argv[1], ttl); err != nil {
+			return err
+		}
+
+		break
+	case "mg", "md", "ma", "ms":
+		var res *MetaResult
+
+		if c.proto.name() != protocolASCII {
+			return fmt.Errorf("meta commands are only supported on ascii protocol")
+		}
+
+		if len(cmds.argv) < 2 {
+			return fmt.Errorf("key must needed")
+		}
+
+		flags, err := ParseMetaFlags(cmds.argv[2:])
+		if err != nil {
+			return err
+		}
+
+		switch cmds.argv[0] {
+		case "mg":
+			res, err = c.MetaGet(cmds.argv[1], flags)
+		case "md":
+			res, err = c.MetaDelete(cmds.argv[1], flags)
+		case "ma":
+			res, err = c.MetaArithmetic(cmds.argv[1], flags)
+		case "ms":
+			var value []byte
+
+			value, err = c.commandValue(cmds)
+			if err != nil {
+				return err
+			}
+
+			res, err = c.MetaSet(cmds.argv[1], value, flags)
+		}
+		if err != nil {
+			return err
+		}
+
+		c.printMetaResult(cmds.argv[1], res)
+
+		break
+	case "me":
+		if c.proto.name() != protocolASCII {
+			return fmt.Errorf("meta commands are only supported on ascii protocol")
+		}
+
+		if len(cmds.argv) < 2 {
+			return fmt.Errorf("key must needed")
+		}
+
+		res, err := c.MetaDebug(cmds.argv[1], len(cmds.argv) > 2 && cmds.argv[2] == "b")
+		if err != nil {
+			return err
+		}
+
+		c.printMetaResult(cmds.argv[1], res)
+
+		break
+	case "mn":
+		if c.proto.name() != protocolASCII {
+			return fmt.Errorf("meta commands are only supported on ascii protocol")
+		}
+
+		if err := c.MetaNoop(); err != nil {
+			return err
+		}
+
+		c.printResult("MN", field{"status", "MN"})
 
 		break
 	case "del", "delete", "rm", "remove":
@@ -311,7 +789,61 @@ func (c *Client) Run(cmds *cmds) error {
 				return err
 			}
 
-			fmt.Printf("key %s deleted\n", cmds.argv[i])
+			c.printResult(fmt.Sprintf("key %s deleted", cmds.argv[i]),
+				field{"command", "delete"}, field{"key", cmds.argv[i]}, field{"status", "deleted"})
+		}
+
+		break
+	case "version":
+		version, err := c.Version()
+		if err != nil {
+			return err
+		}
+
+		c.printResult(fmt.Sprintf("memcached %s (%s protocol)", version, c.proto.name()),
+			field{"version", version}, field{"protocol", c.proto.name()})
+
+		break
+	case "stats":
+		stats, err := c.proto.stats(strings.Join(cmds.argv[1:], " "))
+		if err != nil {
+			return err
+		}
+
+		for _, st := range stats {
+			c.printResult(fmt.Sprintf("%s : %s", st.name, st.value), field{"name", st.name}, field{"value", st.value})
+		}
+
+		break
+	case "output":
+		if len(cmds.argv) < 2 {
+			fmt.Printf("output format is %s\n", c.output)
+			break
+		}
+
+		if err := c.setOutput(cmds.argv[1]); err != nil {
+			return err
+		}
+
+		// keep output of script parsable
+		if !c.batch {
+			fmt.Printf("output format is changed to %s\n", c.output)
+		}
+
+		break
+	case "display":
+		if len(cmds.argv) < 2 {
+			fmt.Printf("display mode is %s\n", c.display)
+			break
+		}
+
+		if err := c.setDisplay(cmds.argv[1]); err != nil {
+			return err
+		}
+
+		// keep output of script parsable
+		if !c.batch {
+			fmt.Printf("display mode is changed to %s\n", c.display)
 		}
 
 		break
@@ -320,7 +852,7 @@ func (c *Client) Run(cmds *cmds) error {
 			return err
 		}
 
-		fmt.Println("All keys deleted")
+		c.printResult("All keys deleted", field{"command", "flush_all"}, field{"status", "ok"})
 
 		break
 	case "incr", "decr":
@@ -337,7 +869,8 @@ func (c *Client) Run(cmds *cmds) error {
 			return err
 		}
 
-		fmt.Printf("%s: %s\n", cmds.argv[1], res)
+		value, _ := strconv.ParseUint(res, 10, 64)
+		c.printResult(fmt.Sprintf("%s: %s", cmds.argv[1], res), field{"key", cmds.argv[1]}, field{"value", value})
 
 		break
 	default:
@@ -353,6 +886,10 @@ func (c *Client) Close(silent bool) error {
 		fmt.Println("exit mccat terminal")
 	}
 
+	if c.out != nil {
+		c.out.close()
+	}
+
 	if c.historyFile != nil {
 		if err := c.historyFile.Close(); err != nil {
 			return err
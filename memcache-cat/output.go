@@ -0,0 +1,290 @@
+package mccat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// output formats
+const (
+	outputTable = "table"
+	outputRaw   = "raw"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+)
+
+var outputFormats = []string{outputTable, outputRaw, outputJSON, outputJSONL, outputCSV, outputTSV}
+
+// field is named value of record
+type field struct {
+	name  string
+	value interface{}
+}
+
+// record is one result of command.
+// text is written by table format, and value is written by raw format instead of text
+type record struct {
+	text     string
+	fields   []field
+	value    []byte
+	hasValue bool
+}
+
+// formatter write records of command result
+type formatter interface {
+	write(r *record)
+	// flush is called at the end of each command
+	flush()
+	// close end document of output. it is called when output format is changed, client is closed
+	// and after each command of interactive console
+	close()
+}
+
+func newFormatter(name string, w io.Writer) (formatter, error) {
+	switch strings.ToLower(name) {
+	case "", outputTable:
+		return &tableFormatter{w: w}, nil
+	case outputRaw:
+		return &rawFormatter{w: w}, nil
+	case outputJSON:
+		return &jsonFormatter{w: w}, nil
+	case outputJSONL, "jsonlines":
+		return &jsonlFormatter{w: w}, nil
+	case outputCSV:
+		return &csvFormatter{w: csv.NewWriter(w)}, nil
+	case outputTSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+
+		return &csvFormatter{w: cw}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s (%s)", name, strings.Join(outputFormats, ", "))
+	}
+}
+
+// isOutputFormat check name is output format
+func isOutputFormat(name string) bool {
+	_, err := newFormatter(name, nil)
+
+	return name != "" && err == nil
+}
+
+// isStructuredOutput check format is machine readable (errors are written to stderr)
+func isStructuredOutput(name string) bool {
+	switch strings.ToLower(name) {
+	case "", outputTable, outputRaw:
+		return false
+	}
+
+	return true
+}
+
+// tableFormatter write human readable text
+type tableFormatter struct {
+	w io.Writer
+}
+
+func (f *tableFormatter) write(r *record) {
+	fmt.Fprintln(f.w, r.text)
+}
+
+func (f *tableFormatter) flush() {}
+
+func (f *tableFormatter) close() {}
+
+// rawFormatter write value as raw bytes for pipe them to other commands
+type rawFormatter struct {
+	w io.Writer
+}
+
+func (f *rawFormatter) write(r *record) {
+	if r.hasValue {
+		f.w.Write(r.value)
+		return
+	}
+
+	fmt.Fprintln(f.w, r.text)
+}
+
+func (f *rawFormatter) flush() {}
+
+func (f *rawFormatter) close() {}
+
+// jsonObject encode fields to JSON object keeping order of fields.
+// value which is not valid UTF-8 is encoded by base64 with <name>_encoding field
+func jsonObject(fields []field) string {
+	tokens := make([]string, 0, len(fields))
+
+	for _, fd := range fields {
+		v := fd.value
+
+		if b, ok := v.([]byte); ok {
+			if utf8.Valid(b) {
+				v = string(b)
+			} else {
+				tokens = append(tokens, jsonToken(fd.name+"_encoding")+":"+jsonToken("base64"))
+			}
+		}
+
+		tokens = append(tokens, jsonToken(fd.name)+":"+jsonToken(v))
+	}
+
+	return "{" + strings.Join(tokens, ",") + "}"
+}
+
+func jsonToken(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	return string(b)
+}
+
+// jsonFormatter stream records as one JSON array until close
+// (all commands of -c and script are written in one array)
+type jsonFormatter struct {
+	w       io.Writer
+	started bool
+}
+
+func (f *jsonFormatter) write(r *record) {
+	sep := ",\n  "
+	if !f.started {
+		sep = "[\n  "
+		f.started = true
+	}
+
+	fmt.Fprint(f.w, sep+jsonObject(r.fields))
+}
+
+func (f *jsonFormatter) flush() {}
+
+func (f *jsonFormatter) close() {
+	if !f.started {
+		return
+	}
+
+	fmt.Fprint(f.w, "\n]\n")
+	f.started = false
+}
+
+// jsonlFormatter write each record as one JSON line
+type jsonlFormatter struct {
+	w io.Writer
+}
+
+func (f *jsonlFormatter) write(r *record) {
+	fmt.Fprintln(f.w, jsonObject(r.fields))
+}
+
+func (f *jsonlFormatter) flush() {}
+
+func (f *jsonlFormatter) close() {}
+
+// csvFormatter write records as CSV (or TSV) rows.
+// header row is written at first record and when columns are changed
+type csvFormatter struct {
+	w      *csv.Writer
+	header []string
+}
+
+func (f *csvFormatter) write(r *record) {
+	names := make([]string, 0, len(r.fields))
+	values := make([]string, 0, len(r.fields))
+
+	for _, fd := range r.fields {
+		names = append(names, fd.name)
+
+		switch v := fd.value.(type) {
+		case []byte:
+			values = append(values, string(v))
+		case json.RawMessage:
+			values = append(values, string(v))
+		default:
+			values = append(values, fmt.Sprint(fd.value))
+		}
+	}
+
+	if strings.Join(names, ",") != strings.Join(f.header, ",") {
+		f.w.Write(names)
+		f.header = names
+	}
+
+	f.w.Write(values)
+}
+
+func (f *csvFormatter) flush() {
+	f.w.Flush()
+	f.header = nil
+}
+
+func (f *csvFormatter) close() {}
+
+// setOutput change output format of client
+func (c *Client) setOutput(name string) error {
+	out, err := newFormatter(name, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = outputTable
+	}
+
+	if c.out != nil {
+		c.out.close()
+	}
+
+	c.out = out
+	c.output = strings.ToLower(name)
+
+	return nil
+}
+
+// printResult write result of command. text is used by table and raw format
+func (c *Client) printResult(text string, fields ...field) {
+	c.out.write(&record{text: text, fields: fields})
+}
+
+// printError write error of part of command (ex: cache missed key).
+// it is written to stderr in batch mode and machine readable format for keep stdout parsable
+func (c *Client) printError(text string) {
+	if c.batch || isStructuredOutput(c.output) {
+		os.Stderr.WriteString(text + "\n")
+		return
+	}
+
+	fmt.Println(text)
+}
+
+// itemFields return fields of item for machine readable format
+func itemFields(item *Item) []field {
+	fields := []field{
+		{"key", item.Key},
+		{"flags", item.Flags},
+		{"size", item.Size},
+	}
+	if item.CAS != 0 {
+		fields = append(fields, field{"cas", item.CAS})
+	}
+	if item.Compression != "" {
+		fields = append(fields, field{"compression", item.Compression})
+	}
+
+	// decoded value is JSON, so it is embedded as object in JSON format
+	if item.Serialization != "" {
+		fields = append(fields, field{"serialization", item.Serialization})
+
+		return append(fields, field{"value", json.RawMessage(item.Value)})
+	}
+
+	return append(fields, field{"value", item.Value})
+}
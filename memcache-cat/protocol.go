@@ -0,0 +1,77 @@
+package mccat
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	protocolASCII  = "ascii"
+	protocolBinary = "binary"
+)
+
+// maxKeyLength is max length of key of memcached
+const maxKeyLength = 250
+
+// checkKey check key can be written in command line of text protocol.
+// key must not be empty, longer than 250 bytes, or contain spaces and control characters
+// (they split command line, so rest of key is run as other command)
+func checkKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if len(key) > maxKeyLength {
+		return fmt.Errorf("key must not be longer than %d bytes: %d bytes", maxKeyLength, len(key))
+	}
+
+	for i := 0; i < len(key); i++ {
+		if b := key[i]; b <= ' ' || b == 0x7f {
+			return fmt.Errorf("key must not contain spaces or control characters: %q", key)
+		}
+	}
+
+	return nil
+}
+
+// stat is one line of stats response (name and value)
+type stat struct {
+	name  string
+	value string
+}
+
+// protocol is wire protocol used by Client for communicate with memcached server
+type protocol interface {
+	name() string
+	// retrieve execute get, gets, gat or gats. ttl is only used by gat and gats
+	retrieve(cmd string, key string, ttl int) (*Item, error)
+	getMulti(keys []string) (map[string]*Item, error)
+	// store execute set, add, replace, append, prepend or cas
+	store(cmd string, item *Item) error
+	delete(key string) error
+	incrDecr(cmd string, key string, delta uint64) (uint64, error)
+	touch(key string, ttl int) error
+	flushAll() error
+	stats(args string) ([]stat, error)
+	version() (string, error)
+	authenticate(username string, password string) error
+}
+
+func newProtocol(name string, c *Client) (protocol, error) {
+	switch strings.ToLower(name) {
+	case "", protocolASCII, "text":
+		return &asciiProtocol{c: c}, nil
+	case protocolBinary, "bin":
+		return &binaryProtocol{c: c}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %s (ascii or binary)", name)
+	}
+}
+
+// storeError make error message of failed storage command
+func storeError(cmd string) error {
+	if cmd == "add" {
+		return fmt.Errorf("failed to %s: key exist", cmd)
+	}
+
+	return fmt.Errorf("failed to %s: key does not exist", cmd)
+}
@@ -0,0 +1,364 @@
+package mccat
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// asciiProtocol is memcached text protocol
+type asciiProtocol struct {
+	c *Client
+}
+
+func (p *asciiProtocol) name() string {
+	return protocolASCII
+}
+
+func (p *asciiProtocol) retrieve(cmd string, key string, ttl int) (*Item, error) {
+	var item *Item
+
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	line := fmt.Sprintf("%s %s", cmd, key)
+	if cmd == "gat" || cmd == "gats" {
+		line = fmt.Sprintf("%s %d %s", cmd, ttl, key)
+	}
+
+	err := p.c.Write(line)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		buff, err := p.c.Read()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+		}
+
+		if buff == "END" {
+			break
+		}
+		// VALUE is checked first because key may contain "ERROR"
+		if strings.HasPrefix(buff, "VALUE ") {
+			item, err = p.readItem(buff)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+		if isErrorResponse(buff) {
+			return nil, fmt.Errorf("got error on get data of key [%s] from memcached server", key)
+		}
+
+		return nil, fmt.Errorf("got unexpected response on get data of key [%s]: %s", key, buff)
+	}
+
+	if item == nil {
+		return nil, ErrCacheMiss
+	}
+
+	return item, nil
+}
+
+// getMulti send all keys by one get command and read VALUE blocks until END
+func (p *asciiProtocol) getMulti(keys []string) (map[string]*Item, error) {
+	items := make(map[string]*Item)
+
+	if len(keys) == 0 {
+		return items, nil
+	}
+
+	for _, key := range keys {
+		if err := checkKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	err := p.c.Write("get " + strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		buff, err := p.c.Read()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+		}
+
+		if buff == "END" {
+			break
+		}
+		// VALUE is checked first because key may contain "ERROR"
+		if strings.HasPrefix(buff, "VALUE ") {
+			item, err := p.readItem(buff)
+			if err != nil {
+				return nil, err
+			}
+			items[item.Key] = item
+
+			continue
+		}
+		if isErrorResponse(buff) {
+			return nil, fmt.Errorf("got error on get data of %d keys from memcached server: %s", len(keys), buff)
+		}
+
+		return nil, fmt.Errorf("got unexpected response on get data of %d keys: %s", len(keys), buff)
+	}
+
+	return items, nil
+}
+
+// readItem parse VALUE header line and read exact length data block follows it
+// VALUE <key> <flags> <bytes> [<cas unique>]
+func (p *asciiProtocol) readItem(header string) (*Item, error) {
+	f := strings.Fields(header)
+	if len(f) < 4 || f[0] != "VALUE" {
+		return nil, fmt.Errorf("got malformed value header from memcached server: %s", header)
+	}
+
+	flags, err := strconv.ParseUint(f[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("got malformed flags in value header [%s]: %s", header, err.Error())
+	}
+
+	size, err := strconv.Atoi(f[3])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("got malformed length in value header [%s]", header)
+	}
+
+	value, err := p.c.ReadBlock(size)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Item{Key: f[1], Value: value, Flags: uint32(flags), Size: size}
+
+	// cas unique is only included on gets response
+	if len(f) > 4 {
+		item.CAS, err = strconv.ParseUint(f[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("got malformed cas unique in value header [%s]: %s", header, err.Error())
+		}
+	}
+
+	return item, nil
+}
+
+func (p *asciiProtocol) store(cmd string, item *Item) error {
+	if err := checkKey(item.Key); err != nil {
+		return err
+	}
+
+	line := fmt.Sprintf("%s %s %d %d %d", cmd, item.Key, item.Flags, item.TTL, len(item.Value))
+	if cmd == "cas" {
+		line = fmt.Sprintf("%s %d", line, item.CAS)
+	}
+
+	err := p.c.WriteBlock(line, item.Value)
+	if err != nil {
+		return err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if strings.HasPrefix(buff, "NOT_STORED") {
+		return storeError(cmd)
+	}
+	if strings.HasPrefix(buff, "EXISTS") {
+		return ErrCASConflict
+	}
+	if strings.HasPrefix(buff, "NOT_FOUND") {
+		return ErrCacheMiss
+	}
+	if strings.Contains(buff, "ERROR") {
+		return fmt.Errorf("got error on %s value to memcached server", cmd)
+	}
+
+	return nil
+}
+
+func (p *asciiProtocol) delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	err := p.c.Write(fmt.Sprintf("delete %s", key))
+	if err != nil {
+		return err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if strings.HasPrefix(buff, "NOT_FOUND") {
+		return fmt.Errorf("key %s not found", key)
+	}
+
+	if strings.Contains(buff, "ERROR") {
+		return fmt.Errorf("got error on delete key %s from memcached server", key)
+	}
+
+	return nil
+}
+
+func (p *asciiProtocol) incrDecr(cmd string, key string, delta uint64) (uint64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+
+	err := p.c.Write(fmt.Sprintf("%s %s %d", cmd, key, delta))
+	if err != nil {
+		return 0, err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if strings.HasPrefix(buff, "NOT_FOUND") {
+		return 0, fmt.Errorf("key %s not found", key)
+	}
+	if strings.Contains(buff, "ERROR") {
+		return 0, fmt.Errorf("cannot increment or decrement non-numeric value")
+	}
+
+	// decremented number may be padded by spaces
+	value, err := strconv.ParseUint(strings.TrimSpace(buff), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("got unexpected response on %s key %s: %s", cmd, key, buff)
+	}
+
+	return value, nil
+}
+
+func (p *asciiProtocol) touch(key string, ttl int) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	err := p.c.Write(fmt.Sprintf("touch %s %d", key, ttl))
+	if err != nil {
+		return err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if strings.HasPrefix(buff, "NOT_FOUND") {
+		return ErrCacheMiss
+	}
+	if strings.Contains(buff, "ERROR") {
+		return fmt.Errorf("got error on touch key %s from memcached server", key)
+	}
+
+	return nil
+}
+
+func (p *asciiProtocol) flushAll() error {
+	err := p.c.Write("flush_all")
+	if err != nil {
+		return err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if strings.Contains(buff, "ERROR") {
+		return fmt.Errorf("got error on flush all keys from memcached server")
+	}
+
+	return nil
+}
+
+// stats read STAT <name> <value> lines (and ITEM <key> <info> lines of cachedump) until END
+func (p *asciiProtocol) stats(args string) ([]stat, error) {
+	var res []stat
+
+	err := p.c.Write(strings.TrimSpace("stats " + args))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		buff, err := p.c.Read()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+		}
+
+		if strings.HasPrefix(buff, "END") {
+			break
+		}
+		// key of ITEM line (cachedump) may contain "ERROR"
+		if isErrorResponse(buff) {
+			return nil, fmt.Errorf("got error on reading response from memcached server")
+		}
+
+		if strings.HasPrefix(buff, "STAT ") || strings.HasPrefix(buff, "ITEM ") {
+			f := strings.SplitN(buff, " ", 3)
+			if len(f) < 3 {
+				f = append(f, "")
+			}
+
+			res = append(res, stat{name: f[1], value: f[2]})
+		}
+	}
+
+	return res, nil
+}
+
+func (p *asciiProtocol) version() (string, error) {
+	err := p.c.Write("version")
+	if err != nil {
+		return "", err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if !strings.HasPrefix(buff, "VERSION ") {
+		return "", fmt.Errorf("got error on get version from memcached server: %s", buff)
+	}
+
+	return strings.TrimPrefix(buff, "VERSION "), nil
+}
+
+// authenticate send credentials by set command for server started with -Y authfile option
+func (p *asciiProtocol) authenticate(username string, password string) error {
+	token := []byte(username + " " + password)
+
+	err := p.c.WriteBlock(fmt.Sprintf("set auth 0 0 %d", len(token)), token)
+	if err != nil {
+		return err
+	}
+
+	buff, err := p.c.Read()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if strings.HasPrefix(buff, "CLIENT_ERROR") {
+		return fmt.Errorf("authentication failed for user %s: invalid username or password", username)
+	}
+	if !strings.HasPrefix(buff, "STORED") {
+		return fmt.Errorf("authentication failed for user %s: got unexpected response %s", username, buff)
+	}
+
+	return nil
+}
@@ -0,0 +1,529 @@
+package mccat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// binary protocol magic bytes
+const (
+	binaryRequestMagic  = 0x80
+	binaryResponseMagic = 0x81
+	binaryHeaderLen     = 24
+)
+
+// binary protocol opcodes
+const (
+	opGet       = 0x00
+	opSet       = 0x01
+	opAdd       = 0x02
+	opReplace   = 0x03
+	opDelete    = 0x04
+	opIncrement = 0x05
+	opDecrement = 0x06
+	opQuit      = 0x07
+	opFlush     = 0x08
+	opGetQ      = 0x09
+	opNoop      = 0x0a
+	opVersion   = 0x0b
+	opGetK      = 0x0c
+	opGetKQ     = 0x0d
+	opAppend    = 0x0e
+	opPrepend   = 0x0f
+	opStat      = 0x10
+	opSetQ      = 0x11
+	opAddQ      = 0x12
+	opReplaceQ  = 0x13
+	opDeleteQ   = 0x14
+	opIncrQ     = 0x15
+	opDecrQ     = 0x16
+	opQuitQ     = 0x17
+	opFlushQ    = 0x18
+	opAppendQ   = 0x19
+	opPrependQ  = 0x1a
+	opTouch     = 0x1c
+	opGAT       = 0x1d
+	opGATQ      = 0x1e
+	opSASLList  = 0x20
+	opSASLAuth  = 0x21
+	opSASLStep  = 0x22
+	opGATK      = 0x23
+	opGATKQ     = 0x24
+)
+
+// binary protocol response status
+const (
+	statusNoError       = 0x0000
+	statusKeyNotFound   = 0x0001
+	statusKeyExists     = 0x0002
+	statusValueTooLarge = 0x0003
+	statusInvalidArgs   = 0x0004
+	statusNotStored     = 0x0005
+	statusNonNumeric    = 0x0006
+	statusAuthError     = 0x0020
+	statusAuthContinue  = 0x0021
+	statusUnknownCmd    = 0x0081
+	statusOutOfMemory   = 0x0082
+)
+
+var binaryStatusMessages = map[uint16]string{
+	statusKeyNotFound:   "key not found",
+	statusKeyExists:     "key exists",
+	statusValueTooLarge: "value too large",
+	statusInvalidArgs:   "invalid arguments",
+	statusNotStored:     "item not stored",
+	statusNonNumeric:    "incr/decr on non-numeric value",
+	statusAuthError:     "authentication error",
+	statusAuthContinue:  "authentication continue",
+	statusUnknownCmd:    "unknown command",
+	statusOutOfMemory:   "out of memory",
+}
+
+var binaryStoreOpcodes = map[string]byte{
+	"set":     opSet,
+	"add":     opAdd,
+	"replace": opReplace,
+	"append":  opAppend,
+	"prepend": opPrepend,
+	"cas":     opSet,
+}
+
+// binaryPacket is request or response packet of binary protocol
+type binaryPacket struct {
+	opcode byte
+	status uint16
+	opaque uint32
+	cas    uint64
+	extras []byte
+	key    []byte
+	value  []byte
+}
+
+// binaryProtocol is memcached binary protocol
+type binaryProtocol struct {
+	c      *Client
+	opaque uint32
+}
+
+func (p *binaryProtocol) name() string {
+	return protocolBinary
+}
+
+// send write request packet to buffer. caller must flush buffer
+func (p *binaryProtocol) send(req *binaryPacket) error {
+	header := make([]byte, binaryHeaderLen)
+
+	p.c.setDeadline()
+	p.opaque++
+	req.opaque = p.opaque
+
+	header[0] = binaryRequestMagic
+	header[1] = req.opcode
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(req.key)))
+	header[4] = byte(len(req.extras))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(req.extras)+len(req.key)+len(req.value)))
+	binary.BigEndian.PutUint32(header[12:16], req.opaque)
+	binary.BigEndian.PutUint64(header[16:24], req.cas)
+
+	for _, b := range [][]byte{header, req.extras, req.key, req.value} {
+		if _, err := p.c.buff.Writer.Write(b); err != nil {
+			p.c.broken = true
+			return fmt.Errorf("failed on sending command to memcached server: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (p *binaryProtocol) flush() error {
+	if err := p.c.buff.Writer.Flush(); err != nil {
+		p.c.broken = true
+		return fmt.Errorf("failed on sending command to memcached server: %s", err.Error())
+	}
+
+	return nil
+}
+
+// receive read one response packet
+func (p *binaryProtocol) receive() (*binaryPacket, error) {
+	header := make([]byte, binaryHeaderLen)
+
+	if _, err := io.ReadFull(p.c.buff.Reader, header); err != nil {
+		p.c.broken = true
+		return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	if header[0] != binaryResponseMagic {
+		return nil, fmt.Errorf("got wrong magic byte 0x%02x from memcached server (server may not support binary protocol)", header[0])
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:4]))
+	extrasLen := int(header[4])
+	bodyLen := int(binary.BigEndian.Uint32(header[8:12]))
+
+	if bodyLen < keyLen+extrasLen {
+		return nil, fmt.Errorf("got malformed response packet from memcached server")
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(p.c.buff.Reader, body); err != nil {
+		p.c.broken = true
+		return nil, fmt.Errorf("failed on reading response from memcached server: %s", err.Error())
+	}
+
+	return &binaryPacket{
+		opcode: header[1],
+		status: binary.BigEndian.Uint16(header[6:8]),
+		opaque: binary.BigEndian.Uint32(header[12:16]),
+		cas:    binary.BigEndian.Uint64(header[16:24]),
+		extras: body[:extrasLen],
+		key:    body[extrasLen : extrasLen+keyLen],
+		value:  body[extrasLen+keyLen:],
+	}, nil
+}
+
+// roundTrip send request and read its response
+func (p *binaryProtocol) roundTrip(req *binaryPacket) (*binaryPacket, error) {
+	if err := p.send(req); err != nil {
+		return nil, err
+	}
+
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+
+	return p.receive()
+}
+
+// err convert response status to error
+func (res *binaryPacket) err() error {
+	switch res.status {
+	case statusNoError:
+		return nil
+	case statusKeyNotFound:
+		return ErrCacheMiss
+	}
+
+	msg, ok := binaryStatusMessages[res.status]
+	if !ok {
+		msg = fmt.Sprintf("unknown status 0x%04x", res.status)
+	}
+
+	if len(res.value) > 0 {
+		return fmt.Errorf("got error from memcached server: %s (%s)", msg, res.value)
+	}
+
+	return fmt.Errorf("got error from memcached server: %s", msg)
+}
+
+// item make Item from get response
+func (res *binaryPacket) item(key string) *Item {
+	item := &Item{
+		Key:   key,
+		Value: res.value,
+		CAS:   res.cas,
+		Size:  len(res.value),
+	}
+
+	if len(res.extras) >= 4 {
+		item.Flags = binary.BigEndian.Uint32(res.extras[0:4])
+	}
+
+	return item
+}
+
+func expirationExtras(ttl int) []byte {
+	extras := make([]byte, 4)
+	binary.BigEndian.PutUint32(extras, uint32(ttl))
+
+	return extras
+}
+
+func (p *binaryProtocol) retrieve(cmd string, key string, ttl int) (*Item, error) {
+	req := &binaryPacket{opcode: opGetK, key: []byte(key)}
+
+	if cmd == "gat" || cmd == "gats" {
+		req.opcode = opGATK
+		req.extras = expirationExtras(ttl)
+	}
+
+	res, err := p.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.err(); err != nil {
+		return nil, err
+	}
+
+	return res.item(key), nil
+}
+
+// getMulti send quiet getk for each keys and noop for detect end of responses
+func (p *binaryProtocol) getMulti(keys []string) (map[string]*Item, error) {
+	items := make(map[string]*Item)
+
+	for _, key := range keys {
+		if err := p.send(&binaryPacket{opcode: opGetKQ, key: []byte(key)}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.send(&binaryPacket{opcode: opNoop}); err != nil {
+		return nil, err
+	}
+
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+
+	// quiet get does not send response on cache miss.
+	// keep reading until noop response even if got error, for not to leave responses on connection
+	var resErr error
+
+	for {
+		res, err := p.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		if res.opcode == opNoop {
+			break
+		}
+
+		if err := res.err(); err != nil {
+			if err != ErrCacheMiss && resErr == nil {
+				resErr = err
+			}
+
+			continue
+		}
+
+		items[string(res.key)] = res.item(string(res.key))
+	}
+
+	if resErr != nil {
+		return nil, resErr
+	}
+
+	return items, nil
+}
+
+func (p *binaryProtocol) store(cmd string, item *Item) error {
+	opcode, ok := binaryStoreOpcodes[cmd]
+	if !ok {
+		return fmt.Errorf("unknown storage command %s", cmd)
+	}
+
+	req := &binaryPacket{opcode: opcode, key: []byte(item.Key), value: item.Value}
+
+	// append and prepend do not have flags and expiration
+	if opcode != opAppend && opcode != opPrepend {
+		req.extras = make([]byte, 8)
+		binary.BigEndian.PutUint32(req.extras[0:4], item.Flags)
+		binary.BigEndian.PutUint32(req.extras[4:8], uint32(item.TTL))
+	}
+
+	if cmd == "cas" {
+		req.cas = item.CAS
+	}
+
+	res, err := p.roundTrip(req)
+	if err != nil {
+		return err
+	}
+
+	switch res.status {
+	case statusNoError:
+		return nil
+	case statusKeyExists:
+		if cmd == "cas" {
+			return ErrCASConflict
+		}
+
+		return storeError(cmd)
+	case statusKeyNotFound:
+		if cmd == "cas" {
+			return ErrCacheMiss
+		}
+
+		return storeError(cmd)
+	case statusNotStored:
+		return storeError(cmd)
+	}
+
+	return res.err()
+}
+
+func (p *binaryProtocol) delete(key string) error {
+	res, err := p.roundTrip(&binaryPacket{opcode: opDelete, key: []byte(key)})
+	if err != nil {
+		return err
+	}
+
+	if res.status == statusKeyNotFound {
+		return fmt.Errorf("key %s not found", key)
+	}
+
+	return res.err()
+}
+
+func (p *binaryProtocol) incrDecr(cmd string, key string, delta uint64) (uint64, error) {
+	req := &binaryPacket{opcode: opIncrement, key: []byte(key), extras: make([]byte, 20)}
+	if cmd == "decr" {
+		req.opcode = opDecrement
+	}
+
+	// delta, initial value and expiration (0xffffffff means do not create item when not exist)
+	binary.BigEndian.PutUint64(req.extras[0:8], delta)
+	binary.BigEndian.PutUint64(req.extras[8:16], 0)
+	binary.BigEndian.PutUint32(req.extras[16:20], 0xffffffff)
+
+	res, err := p.roundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+
+	switch res.status {
+	case statusKeyNotFound:
+		return 0, fmt.Errorf("key %s not found", key)
+	case statusNonNumeric:
+		return 0, fmt.Errorf("cannot increment or decrement non-numeric value")
+	}
+
+	if err := res.err(); err != nil {
+		return 0, err
+	}
+
+	if len(res.value) != 8 {
+		return 0, fmt.Errorf("got malformed response on %s key %s", cmd, key)
+	}
+
+	return binary.BigEndian.Uint64(res.value), nil
+}
+
+func (p *binaryProtocol) touch(key string, ttl int) error {
+	res, err := p.roundTrip(&binaryPacket{opcode: opTouch, key: []byte(key), extras: expirationExtras(ttl)})
+	if err != nil {
+		return err
+	}
+
+	return res.err()
+}
+
+func (p *binaryProtocol) flushAll() error {
+	res, err := p.roundTrip(&binaryPacket{opcode: opFlush})
+	if err != nil {
+		return err
+	}
+
+	return res.err()
+}
+
+// stats read stat packets until packet with empty key.
+// cachedump is returned by single packet as text protocol format, so parse it like text protocol
+func (p *binaryProtocol) stats(args string) ([]stat, error) {
+	var res []stat
+
+	req := &binaryPacket{opcode: opStat, key: []byte(args)}
+
+	if err := p.send(req); err != nil {
+		return nil, err
+	}
+
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+
+	for {
+		pkt, err := p.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := pkt.err(); err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(args, "cachedump") {
+			for _, line := range bytes.Split(pkt.value, []byte("\r\n")) {
+				f := strings.SplitN(string(line), " ", 3)
+				if len(f) == 3 && f[0] == "ITEM" {
+					res = append(res, stat{name: f[1], value: f[2]})
+				}
+			}
+
+			break
+		}
+
+		if len(pkt.key) == 0 {
+			break
+		}
+
+		res = append(res, stat{name: string(pkt.key), value: string(pkt.value)})
+	}
+
+	return res, nil
+}
+
+func (p *binaryProtocol) version() (string, error) {
+	res, err := p.roundTrip(&binaryPacket{opcode: opVersion})
+	if err != nil {
+		return "", err
+	}
+
+	if err := res.err(); err != nil {
+		return "", err
+	}
+
+	return string(res.value), nil
+}
+
+// authenticate do SASL PLAIN authentication after check server supported mechanisms
+func (p *binaryProtocol) authenticate(username string, password string) error {
+	res, err := p.roundTrip(&binaryPacket{opcode: opSASLList})
+	if err != nil {
+		return err
+	}
+
+	if res.status == statusUnknownCmd {
+		return fmt.Errorf("server does not support SASL authentication (started without -S option?)")
+	}
+	if err := res.err(); err != nil {
+		return err
+	}
+
+	mechs := strings.Fields(string(res.value))
+	supported := false
+	for _, mech := range mechs {
+		if mech == "PLAIN" {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("server does not support SASL PLAIN mechanism (supported: %s)", strings.Join(mechs, ", "))
+	}
+
+	// PLAIN message is [authzid] NUL authcid NUL passwd
+	res, err = p.roundTrip(&binaryPacket{
+		opcode: opSASLAuth,
+		key:    []byte("PLAIN"),
+		value:  []byte("\x00" + username + "\x00" + password),
+	})
+	if err != nil {
+		return err
+	}
+
+	if res.status == statusAuthError {
+		return fmt.Errorf("authentication failed for user %s: invalid username or password", username)
+	}
+	if res.status == statusAuthContinue {
+		return fmt.Errorf("authentication failed for user %s: server requested unsupported SASL step", username)
+	}
+
+	return res.err()
+}